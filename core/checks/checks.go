@@ -0,0 +1,319 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package checks runs preflight checks (e.g. kubeconfig validity, cluster reachability, tool versions, disk space)
+// concurrently and reports their results consistently through Output operations.
+//
+// Example:
+//
+//	registry := checks.NewRegistry()
+//	_ = registry.Register(checks.Check{
+//		Name:        "kubeconfig",
+//		Description: "Kubeconfig is valid",
+//		Run:         validateKubeconfig,
+//	}, checks.Check{
+//		Name:        "cluster",
+//		Description: "Cluster is reachable",
+//		DependsOn:   []string{"kubeconfig"},
+//		Run:         pingCluster,
+//	})
+//	rootCmd.AddCommand(checks.NewDoctorCommand(rootOpts.Output, registry))
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/internal/dag"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// Severity defines how a failing check affects the overall result.
+type Severity string
+
+const (
+	// SeverityError fails the overall result if the check fails.
+	SeverityError Severity = "error"
+	// SeverityWarning reports a failing check as warning, without failing the overall result.
+	SeverityWarning Severity = "warning"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusWarning Status = "warning"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// Check describes a single check.
+type Check struct {
+	// Name uniquely identifies the check, e.g. for dependencies.
+	Name string
+	// Description is displayed while and after running the check. Defaults to Name.
+	Description string
+	// Severity defines how a failure affects the overall result. Defaults to SeverityError.
+	Severity Severity
+	// DependsOn lists names of checks that have to pass (or warn) before this check runs. Otherwise this check is
+	// skipped.
+	DependsOn []string
+	// Run executes the check. Return nil if the check passed, Skip or Warn to explicitly skip or warn, or any other
+	// error if the check failed.
+	Run func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Severity    Severity      `json:"severity"`
+	Status      Status        `json:"status"`
+	Message     string        `json:"message,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Report contains the results of all checks, in order of registration.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed returns true if any check failed.
+func (r Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of checks with the given status.
+func (r Report) Count(status Status) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+type skipError struct{ reason string }
+
+func (e *skipError) Error() string { return e.reason }
+
+type warnError struct{ msg string }
+
+func (e *warnError) Error() string { return e.msg }
+
+// Skip returns an error that marks a check as skipped, e.g. because it does not apply to the environment.
+func Skip(reason string) error {
+	return &skipError{reason: reason}
+}
+
+// Warn returns an error that marks a check as warning, regardless of its severity.
+func Warn(msg string) error {
+	return &warnError{msg: msg}
+}
+
+// Registry holds checks.
+type Registry struct {
+	checks []Check
+	lock   sync.RWMutex
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds checks to the registry. Check names must be unique.
+func (r *Registry) Register(checks ...Check) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, check := range checks {
+		if check.Name == "" {
+			return errors.New("check name must not be empty")
+		}
+		if check.Run == nil {
+			return fmt.Errorf("check %q has no run function", check.Name)
+		}
+		for _, existing := range r.checks {
+			if existing.Name == check.Name {
+				return fmt.Errorf("check %q is already registered", check.Name)
+			}
+		}
+		if check.Description == "" {
+			check.Description = check.Name
+		}
+		if check.Severity == "" {
+			check.Severity = SeverityError
+		}
+		r.checks = append(r.checks, check)
+	}
+	return nil
+}
+
+// Checks returns the registered checks in order of registration.
+func (r *Registry) Checks() []Check {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]Check{}, r.checks...)
+}
+
+// RunOptions configure how checks are run.
+type RunOptions struct {
+	// Concurrency limits the number of checks running at the same time. Unlimited if 0.
+	Concurrency int
+	// Only runs only the named checks and the checks they depend on. All checks are run if empty.
+	Only []string
+}
+
+// Run runs the checks concurrently, respecting dependencies, and reports them as operations to out in order of
+// registration. An error is only returned if the checks are invalid, e.g. because of unknown or cyclic dependencies,
+// use Report.Failed to determine if any check failed.
+func (r *Registry) Run(ctx context.Context, out output.Output, opts RunOptions) (Report, error) {
+	checks, err := selectChecks(r.Checks(), opts.Only)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := dag.Validate("check", nodes(checks)); err != nil {
+		return Report{}, err
+	}
+
+	semaphore := dag.NewSemaphore(opts.Concurrency)
+	results := make([]Result, len(checks))
+	schedule := dag.Start(nodes(checks), func(i int, wait func(dependency string) int) {
+		check := checks[i]
+		for _, dependency := range check.DependsOn {
+			if status := results[wait(dependency)].Status; status != StatusPassed && status != StatusWarning {
+				results[i] = newResult(check, StatusSkipped, fmt.Sprintf("dependency %q %s", dependency, status), 0)
+				return
+			}
+		}
+
+		release, err := semaphore.Acquire(ctx)
+		if err != nil {
+			results[i] = newResult(check, StatusSkipped, err.Error(), 0)
+			return
+		}
+		defer release()
+		results[i] = runCheck(ctx, check)
+	})
+
+	for i, check := range checks {
+		out.StartOperation(check.Description)
+		schedule.Wait(i)
+		out.EndOperationWithStatus(endOperationStatus(results[i].Status))
+		if results[i].Message != "" {
+			out.V(1).Info(results[i].Message)
+		}
+	}
+
+	return Report{Results: results}, nil
+}
+
+func runCheck(ctx context.Context, check Check) Result {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return newResult(check, StatusSkipped, err.Error(), 0)
+	}
+
+	err := check.Run(ctx)
+	duration := time.Since(start)
+
+	var skipErr *skipError
+	var warnErr *warnError
+	switch {
+	case err == nil:
+		return newResult(check, StatusPassed, "", duration)
+	case errors.As(err, &skipErr):
+		return newResult(check, StatusSkipped, err.Error(), duration)
+	case errors.As(err, &warnErr), check.Severity == SeverityWarning:
+		return newResult(check, StatusWarning, err.Error(), duration)
+	default:
+		return newResult(check, StatusFailed, err.Error(), duration)
+	}
+}
+
+func newResult(check Check, status Status, message string, duration time.Duration) Result {
+	return Result{
+		Name:        check.Name,
+		Description: check.Description,
+		Severity:    check.Severity,
+		Status:      status,
+		Message:     message,
+		Duration:    duration,
+	}
+}
+
+func endOperationStatus(status Status) output.EndOperationStatus {
+	switch status {
+	case StatusPassed:
+		return output.Success()
+	case StatusWarning:
+		return output.Warning()
+	case StatusSkipped:
+		return output.Skipped()
+	default:
+		return output.Failure()
+	}
+}
+
+// selectChecks returns the named checks and all their transitive dependencies, in order of registration.
+func selectChecks(checks []Check, only []string) ([]Check, error) {
+	if len(only) == 0 {
+		return checks, nil
+	}
+
+	byName := make(map[string]Check, len(checks))
+	for _, check := range checks {
+		byName[check.Name] = check
+	}
+
+	selected := map[string]bool{}
+	var selectCheck func(name string) error
+	selectCheck = func(name string) error {
+		if selected[name] {
+			return nil
+		}
+		check, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown check %q", name)
+		}
+		selected[name] = true
+		for _, dependency := range check.DependsOn {
+			if err := selectCheck(dependency); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range only {
+		if err := selectCheck(name); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Check, 0, len(selected))
+	for _, check := range checks {
+		if selected[check.Name] {
+			result = append(result, check)
+		}
+	}
+	return result, nil
+}
+
+func nodes(checks []Check) []dag.Node {
+	nodes := make([]dag.Node, 0, len(checks))
+	for _, check := range checks {
+		nodes = append(nodes, dag.Node{Name: check.Name, DependsOn: check.DependsOn})
+	}
+	return nodes
+}
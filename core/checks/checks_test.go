@@ -0,0 +1,171 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/checks"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+func pass(ctx context.Context) error { return nil }
+
+func fail(ctx context.Context) error { return errors.New("check failed") }
+
+func newTestRegistry(t *testing.T) *checks.Registry {
+	t.Helper()
+	registry := checks.NewRegistry()
+	require.NoError(t, registry.Register(
+		checks.Check{Name: "kubeconfig", Description: "Kubeconfig is valid", Run: pass},
+		checks.Check{
+			Name:        "cluster",
+			Description: "Cluster is reachable",
+			DependsOn:   []string{"kubeconfig"},
+			Run:         fail,
+		},
+		checks.Check{
+			Name:        "workloads",
+			Description: "Workloads are healthy",
+			DependsOn:   []string{"cluster"},
+			Run:         pass,
+		},
+		checks.Check{Name: "disk", Description: "Enough disk space", Severity: checks.SeverityWarning, Run: fail},
+		checks.Check{Name: "docker", Run: func(ctx context.Context) error {
+			return checks.Skip("docker is not used")
+		}},
+		checks.Check{Name: "version", Run: func(ctx context.Context) error {
+			return checks.Warn("newer version available")
+		}},
+	))
+	return registry
+}
+
+func TestRun(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	errOut := bytes.Buffer{}
+	report, err := registry.Run(
+		context.Background(), output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0), checks.RunOptions{},
+	)
+	require.NoError(t, err)
+
+	statuses := map[string]checks.Status{}
+	for _, result := range report.Results {
+		statuses[result.Name] = result.Status
+	}
+	assert.Equal(t, map[string]checks.Status{
+		"kubeconfig": checks.StatusPassed,
+		"cluster":    checks.StatusFailed,
+		"workloads":  checks.StatusSkipped,
+		"disk":       checks.StatusWarning,
+		"docker":     checks.StatusSkipped,
+		"version":    checks.StatusWarning,
+	}, statuses)
+	assert.True(t, report.Failed())
+	assert.Equal(t, 2, report.Count(checks.StatusWarning))
+	assert.Equal(t, `dependency "cluster" failed`, report.Results[2].Message)
+
+	assert.Regexp(t, "(?s)Kubeconfig is valid.*Cluster is reachable.*Workloads are healthy.*Enough disk space.*"+
+		"docker.*version", errOut.String())
+	assert.Contains(t, errOut.String(), "✓ Kubeconfig is valid")
+	assert.Contains(t, errOut.String(), "✗ Cluster is reachable")
+	assert.Contains(t, errOut.String(), "∅ Workloads are healthy")
+	assert.Contains(t, errOut.String(), "! Enough disk space")
+}
+
+func TestRunOnly(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	report, err := registry.Run(
+		context.Background(), output.NewDiscardingOutput(), checks.RunOptions{Only: []string{"cluster"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, "kubeconfig", report.Results[0].Name)
+	assert.Equal(t, "cluster", report.Results[1].Name)
+
+	_, err = registry.Run(
+		context.Background(), output.NewDiscardingOutput(), checks.RunOptions{Only: []string{"unknown"}},
+	)
+	assert.EqualError(t, err, `unknown check "unknown"`)
+}
+
+func TestRunConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	check := func(ctx context.Context) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if current <= old || atomic.CompareAndSwapInt32(&maxRunning, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	registry := checks.NewRegistry()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		require.NoError(t, registry.Register(checks.Check{Name: name, Run: check}))
+	}
+
+	report, err := registry.Run(context.Background(), output.NewDiscardingOutput(), checks.RunOptions{Concurrency: 2})
+	require.NoError(t, err)
+	assert.False(t, report.Failed())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestInvalidChecks(t *testing.T) {
+	registry := checks.NewRegistry()
+	require.NoError(t, registry.Register(checks.Check{Name: "a", Run: pass}))
+	assert.EqualError(t, registry.Register(checks.Check{Name: "a", Run: pass}), `check "a" is already registered`)
+	assert.EqualError(t, registry.Register(checks.Check{Name: "b"}), `check "b" has no run function`)
+
+	registry = checks.NewRegistry()
+	require.NoError(t, registry.Register(checks.Check{Name: "a", DependsOn: []string{"unknown"}, Run: pass}))
+	_, err := registry.Run(context.Background(), output.NewDiscardingOutput(), checks.RunOptions{})
+	assert.EqualError(t, err, `check "a" depends on unknown check "unknown"`)
+
+	registry = checks.NewRegistry()
+	require.NoError(t, registry.Register(
+		checks.Check{Name: "a", DependsOn: []string{"b"}, Run: pass},
+		checks.Check{Name: "b", DependsOn: []string{"a"}, Run: pass},
+	))
+	_, err = registry.Run(context.Background(), output.NewDiscardingOutput(), checks.RunOptions{})
+	assert.EqualError(t, err, "cyclic check dependencies: a -> b -> a")
+}
+
+func TestDoctorCommand(t *testing.T) {
+	out := bytes.Buffer{}
+	rootCmd := &cobra.Command{Use: "dkp", SilenceErrors: true, SilenceUsage: true}
+	rootCmd.AddCommand(checks.NewDoctorCommand(
+		output.NewNonInteractiveShell(&out, &bytes.Buffer{}, 0), newTestRegistry(t),
+	))
+
+	rootCmd.SetArgs([]string{"doctor", "--output", "json"})
+	err := rootCmd.Execute()
+	assert.EqualError(t, err, "1 of 6 checks failed")
+
+	report := checks.Report{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Len(t, report.Results, 6)
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"doctor", "--check", "kubeconfig", "--output", "yaml"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, out.String(), "status: passed")
+	assert.NotContains(t, out.String(), "cluster")
+}
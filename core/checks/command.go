@@ -0,0 +1,72 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// NewDoctorCommand returns a cobra command running the registered checks. The command fails if any check fails.
+func NewDoctorCommand(out output.Output, registry *Registry) *cobra.Command {
+	var (
+		outputFormat string
+		opts         RunOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for common problems",
+		Long: `Check the environment for common problems.
+Checks whose dependencies fail are skipped. Use "--verbose 1" to see details of failing checks.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "" && outputFormat != "yaml" && outputFormat != "json" {
+				return errors.New(`--output must be 'yaml' or 'json'`)
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			report, err := registry.Run(ctx, out, opts)
+			if err != nil {
+				return err
+			}
+
+			switch outputFormat {
+			case "yaml":
+				marshalled, err := yaml.Marshal(report)
+				if err != nil {
+					return err
+				}
+				out.Result(string(marshalled))
+			case "json":
+				marshalled, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				out.Result(string(marshalled))
+			}
+
+			if report.Failed() {
+				return fmt.Errorf("%d of %d checks failed", report.Count(StatusFailed), len(report.Results))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", outputFormat, "One of 'yaml' or 'json'.")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", opts.Concurrency,
+		"Maximum number of checks to run at the same time. Unlimited if 0.")
+	cmd.Flags().StringSliceVar(&opts.Only, "check", opts.Only,
+		"Only run the named checks (and the checks they depend on).")
+	return cmd
+}
@@ -9,10 +9,12 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
 	"github.com/mesosphere/dkp-cli-runtime/core/customdocs"
 )
 
@@ -23,10 +25,13 @@ func NewHelpCommandWrapper(rootCmd *cobra.Command) *cobra.Command {
 		showTree      bool
 		treeOutputDir string
 		templateFile  string
+		manSection    string
+		manSource     string
+		manManual     string
 	)
 
 	helpCmd := &cobra.Command{
-		Use:   "help [-o {yaml|yml|markdown|md|template|htmltemplate}] [command]",
+		Use:   "help [-o {yaml|yml|markdown|md|man|template|htmltemplate}] [command]",
 		Short: "Help about any command",
 		Long: `Help provides help for any command in the application.
 Simply type ` + rootCmd.Name() + ` help [path to command] for full details.`,
@@ -47,6 +52,15 @@ Simply type ` + rootCmd.Name() + ` help [path to command] for full details.`,
 						return doc.GenMarkdownTree(cmd, treeOutputDir)
 					}
 					return doc.GenMarkdown(cmd, rootCmd.OutOrStdout())
+				case "man":
+					header, err := manHeader(rootCmd, manSection, manSource, manManual)
+					if err != nil {
+						return err
+					}
+					if showTree {
+						return doc.GenManTree(cmd, header, treeOutputDir)
+					}
+					return doc.GenMan(cmd, header, rootCmd.OutOrStdout())
 				case "template", "htmltemplate":
 					tpl, err := loadTemplate(templateFile, outputFormat)
 					if err != nil {
@@ -92,22 +106,63 @@ Simply type ` + rootCmd.Name() + ` help [path to command] for full details.`,
 	helpCmd.Flags().BoolVarP(&showTree, "tree", "t", false, "Generate help for full command tree")
 	helpCmd.Flags().StringVarP(&treeOutputDir, "output-dir", "d", "", "Output for full command tree if --tree=true")
 	helpCmd.Flags().StringVar(&templateFile, "template", "", "template file to use if --format=template or htmltemplate")
+	helpCmd.Flags().StringVar(&manSection, "man-section", "1", "Manual section if --output=man")
+	helpCmd.Flags().StringVar(&manSource, "man-source", "",
+		"Source of the man pages if --output=man, the name and version of the root command if not set")
+	helpCmd.Flags().StringVar(&manManual, "man-manual", "", "Name of the manual if --output=man")
 
 	return helpCmd
 }
 
+// commitDateLayouts are the accepted formats of the commit date set with ldflags, e.g. "2021-12-13 12:52:12 UTC"
+// like `date -u "+%Y-%m-%d %H:%M:%S %Z"`, the output of `git show -s --format=%ci` or RFC 3339.
+var commitDateLayouts = []string{
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// manHeader returns the header of man pages. The date is the commit date of this binary, so the generated pages are
+// reproducible.
+func manHeader(rootCmd *cobra.Command, section, source, manual string) (*doc.GenManHeader, error) {
+	v := version.GetVersion()
+	if source == "" {
+		source = fmt.Sprintf("%s %s", rootCmd.Name(), v.GitVersion)
+	}
+	date, err := parseCommitDate(v.CommitDate)
+	if err != nil {
+		return nil, err
+	}
+	return &doc.GenManHeader{
+		Section: section,
+		Source:  source,
+		Manual:  manual,
+		Date:    &date,
+	}, nil
+}
+
+func parseCommitDate(value string) (time.Time, error) {
+	for _, layout := range commitDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid commit date %q for man pages, expected a format like %q", value,
+		commitDateLayouts[0])
+}
+
 func loadTemplate(templateFile string, format string) (customdocs.Template, error) {
 	if templateFile == "" {
 		return nil, fmt.Errorf("a template file (--template) is required with --format=%s", format)
 	}
 	switch format {
 	case "htmltemplate":
-		return htmltemplate.New(filepath.Base(templateFile)).Funcs(htmltemplate.FuncMap{
+		return htmltemplate.New(filepath.Base(templateFile)).Funcs(customdocs.FuncMap()).Funcs(htmltemplate.FuncMap{
 			//nolint:gosec // helper for XHTML
 			"CDATA": func(text string) htmltemplate.HTML { return htmltemplate.HTML("<![CDATA[" + text + "]]>") },
 		}).ParseFiles(templateFile)
 	case "template":
-		return template.ParseFiles(templateFile)
+		return template.New(filepath.Base(templateFile)).Funcs(customdocs.FuncMap()).ParseFiles(templateFile)
 	default:
 		return nil, fmt.Errorf("unsupported template format: %q", format)
 	}
@@ -0,0 +1,63 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package help
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommitDate(t *testing.T) {
+	expected := time.Date(2021, 12, 13, 12, 52, 12, 0, time.UTC)
+	for _, value := range []string{"2021-12-13 12:52:12 UTC", "2021-12-13 12:52:12 +0000", "2021-12-13T12:52:12Z"} {
+		date, err := parseCommitDate(value)
+		require.NoError(t, err, value)
+		assert.True(t, expected.Equal(date), value)
+	}
+
+	_, err := parseCommitDate("yesterday")
+	assert.EqualError(t, err,
+		`invalid commit date "yesterday" for man pages, expected a format like "2006-01-02 15:04:05 MST"`)
+}
+
+func newTestCommand() *cobra.Command {
+	rootCmd := &cobra.Command{Use: "dkp"}
+	createCmd := &cobra.Command{Use: "create", Short: "Create resources"}
+	createCmd.AddCommand(&cobra.Command{Use: "cluster", Short: "Create a cluster", Run: func(*cobra.Command, []string) {}})
+	rootCmd.AddCommand(createCmd)
+	rootCmd.SetHelpCommand(NewHelpCommandWrapper(rootCmd))
+	return rootCmd
+}
+
+func TestManOutput(t *testing.T) {
+	rootCmd := newTestCommand()
+	out := &bytes.Buffer{}
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"help", "-o", "man", "create", "cluster"})
+	require.NoError(t, rootCmd.Execute())
+
+	// the date is the commit date of the binary, not the current date
+	assert.Contains(t, out.String(), `.TH "DKP-CREATE-CLUSTER" "1" "Jan 1970" "dkp v0.0.0-dev" ""`)
+	assert.Contains(t, out.String(), "dkp-create-cluster - Create a cluster")
+}
+
+func TestManOutputTree(t *testing.T) {
+	rootCmd := newTestCommand()
+	dir := t.TempDir()
+	rootCmd.SetArgs([]string{"help", "-o", "man", "--tree", "-d", dir, "create"})
+	require.NoError(t, rootCmd.Execute())
+
+	for _, name := range []string{"dkp-create.1", "dkp-create-cluster.1"} {
+		page, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Contains(t, string(page), `"Jan 1970" "dkp v0.0.0-dev"`, name)
+	}
+}
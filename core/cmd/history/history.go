@@ -0,0 +1,209 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+)
+
+// options is a struct to support history command.
+type options struct {
+	Command string
+	Since   time.Duration
+	Failed  bool
+	Limit   int
+	Output  string
+
+	outWriter io.Writer
+	journal   *journal.Journal
+}
+
+// NewCommand returns a cobra command for listing, filtering, showing and exporting command journal entries.
+func NewCommand(output io.Writer, j *journal.Journal) *cobra.Command {
+	options := &options{
+		outWriter: output,
+		journal:   j,
+	}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show previously executed commands",
+		Long: `Show previously executed commands recorded in the command journal.
+Values of sensitive flags are redacted. Use "--output json" to export entries, e.g. to attach them to a support
+ticket.`,
+		Args: cobra.NoArgs,
+		Annotations: map[string]string{
+			journal.ExcludeAnnotation: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := options.Validate()
+			if err != nil {
+				return err
+			}
+			return options.Run()
+		},
+	}
+	cmd.Flags().StringVar(&options.Command, "command", options.Command, "Only show commands containing this string.")
+	cmd.Flags().DurationVar(&options.Since, "since", options.Since, "Only show commands executed within this duration.")
+	cmd.Flags().BoolVar(&options.Failed, "failed", options.Failed, "Only show commands that failed.")
+	cmd.Flags().IntVar(&options.Limit, "limit", options.Limit, "Only show this number of latest commands.")
+	cmd.Flags().StringVarP(&options.Output, "output", "o", options.Output, "One of 'yaml' or 'json'.")
+
+	cmd.AddCommand(newShowCommand(output, j))
+	cmd.AddCommand(newClearCommand(j))
+	return cmd
+}
+
+func newShowCommand(output io.Writer, j *journal.Journal) *cobra.Command {
+	options := &options{
+		outWriter: output,
+		journal:   j,
+	}
+	cmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show details of a previously executed command",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid journal entry ID %q", args[0])
+			}
+			if err := options.Validate(); err != nil {
+				return err
+			}
+			entry, err := j.Entry(id)
+			if err != nil {
+				return err
+			}
+			return options.print(entry)
+		},
+	}
+	cmd.Flags().StringVarP(&options.Output, "output", "o", options.Output, "One of 'yaml' or 'json'.")
+	return cmd
+}
+
+func newClearCommand(j *journal.Journal) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all entries from the command journal",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return j.Clear()
+		},
+	}
+}
+
+// Validate validates the provided options.
+func (o *options) Validate() error {
+	if o.Output != "" && o.Output != "yaml" && o.Output != "json" {
+		return errors.New(`--output must be 'yaml' or 'json'`)
+	}
+	if o.Limit < 0 {
+		return errors.New(`--limit must not be negative`)
+	}
+
+	return nil
+}
+
+// Run executes history command.
+func (o *options) Run() error {
+	entries, err := o.journal.Entries()
+	if err != nil {
+		return err
+	}
+
+	filter := journal.Filter{
+		Command:    o.Command,
+		FailedOnly: o.Failed,
+		Limit:      o.Limit,
+	}
+	if o.Since > 0 {
+		filter.Since = time.Now().Add(-o.Since)
+	}
+	return o.print(filter.Apply(entries))
+}
+
+func (o *options) print(v interface{}) error {
+	switch o.Output {
+	case "":
+		switch v := v.(type) {
+		case journal.Entry:
+			printEntry(o.outWriter, v)
+		case []journal.Entry:
+			printTable(o.outWriter, v)
+		}
+	case "yaml":
+		marshalled, err := yaml.Marshal(&v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.outWriter, string(marshalled))
+	case "json":
+		marshalled, err := json.MarshalIndent(&v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.outWriter, string(marshalled))
+	default:
+		// There is a bug in the program if we hit this case.
+		// However, we follow a policy of never panicking.
+		return fmt.Errorf("history options were not validated: --output=%q should have been rejected", o.Output)
+	}
+
+	return nil
+}
+
+func printTable(w io.Writer, entries []journal.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd // Column padding.
+	fmt.Fprintln(tw, "ID\tTIME\tDURATION\tEXIT CODE\tCOMMAND")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n",
+			entry.ID,
+			entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			entry.Duration.Round(time.Millisecond),
+			entry.ExitCode,
+			commandLine(entry),
+		)
+	}
+	tw.Flush()
+}
+
+func printEntry(w io.Writer, entry journal.Entry) {
+	fmt.Fprintf(w, "ID:        %d\n", entry.ID)
+	fmt.Fprintf(w, "Time:      %s\n", entry.Timestamp.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "Command:   %s\n", commandLine(entry))
+	fmt.Fprintf(w, "Duration:  %s\n", entry.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "Exit code: %d\n", entry.ExitCode)
+	if entry.Error != "" {
+		fmt.Fprintf(w, "Error:     %s\n", entry.Error)
+	}
+	fmt.Fprintf(w, "Version:   %s\n", entry.Version)
+}
+
+// commandLine reconstructs the command line of an entry, flags ordered by name.
+func commandLine(entry journal.Entry) string {
+	parts := []string{entry.Command}
+	names := make([]string, 0, len(entry.Flags))
+	for name := range entry.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("--%s=%s", name, entry.Flags[name]))
+	}
+	parts = append(parts, entry.Args...)
+	return strings.Join(parts, " ")
+}
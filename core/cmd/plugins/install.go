@@ -0,0 +1,186 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// NewInstallCommands returns cobra commands for installing, listing, upgrading and removing plugins with the given
+// installer. They are meant to be added to the command returned by NewCommand.
+func NewInstallCommands(out output.Output, installer *plugin.Installer) []*cobra.Command {
+	return []*cobra.Command{
+		newInstallCommand(out, installer),
+		newListCommand(out, installer),
+		newUpgradeCommand(out, installer),
+		newRemoveCommand(out, installer),
+	}
+}
+
+func newInstallCommand(out output.Output, installer *plugin.Installer) *cobra.Command {
+	var version string
+	cmd := &cobra.Command{
+		Use:   "install NAME",
+		Short: "Install a plugin",
+		Long: `Install a plugin from the plugin index.
+An installed version of the plugin is replaced.`,
+		Args: cobra.MatchAll(cobra.ExactArgs(1), pluginNames),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out.StartOperation(fmt.Sprintf("Installing plugin %s", args[0]))
+			installed, err := installer.Install(cmd.Context(), args[0], version)
+			out.EndOperation(err == nil)
+			if err != nil {
+				return err
+			}
+			reportInstalled(out, installed)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&version, "version", "", "Version to install, the latest version if not set")
+	return cmd
+}
+
+func newListCommand(out output.Output, installer *plugin.Installer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List installed and available plugins",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			installed, err := installer.Installed()
+			if err != nil {
+				return err
+			}
+			installedVersions := map[string]string{}
+			for _, p := range installed {
+				installedVersions[p.Name] = p.Version
+			}
+
+			latestVersions := map[string]string{}
+			names := []string{}
+			index, err := installer.LoadIndex(cmd.Context())
+			if err != nil {
+				out.Warnf("Available plugins are unknown: %v", err)
+			} else {
+				for _, entry := range index.Plugins {
+					if _, ok := latestVersions[entry.Name]; !ok {
+						names = append(names, entry.Name)
+					}
+					latest, _ := index.Latest(entry.Name)
+					latestVersions[entry.Name] = latest.Version
+				}
+			}
+			for _, p := range installed {
+				if _, ok := latestVersions[p.Name]; !ok {
+					names = append(names, p.Name)
+				}
+			}
+
+			w := tabwriter.NewWriter(out.ResultWriter(), 0, 0, 3, ' ', 0) //nolint:gomnd // Column padding.
+			fmt.Fprintln(w, "NAME\tINSTALLED\tLATEST")
+			for _, name := range names {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, orNone(installedVersions[name]), orNone(latestVersions[name]))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newUpgradeCommand(out output.Output, installer *plugin.Installer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "upgrade [NAME...]",
+		Short: "Upgrade installed plugins",
+		Long: `Upgrade installed plugins to the latest version in the plugin index.
+All installed plugins are upgraded if no names are given.`,
+		Args: pluginNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := args
+			if len(names) == 0 {
+				installed, err := installer.Installed()
+				if err != nil {
+					return err
+				}
+				for _, p := range installed {
+					names = append(names, p.Name)
+				}
+			}
+
+			if len(names) == 0 {
+				return nil
+			}
+			index, err := installer.LoadIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, name := range names {
+				out.StartOperation(fmt.Sprintf("Upgrading plugin %s", name))
+				upgraded, ok, err := installer.UpgradeFromIndex(cmd.Context(), index, name)
+				switch {
+				case err != nil:
+					out.EndOperation(false)
+					out.Error(err, fmt.Sprintf("Failed to upgrade plugin %s", name))
+					failed++
+				case !ok:
+					out.EndOperationWithStatus(output.Skipped())
+					out.Infof("Plugin %s is up to date (%s)", name, upgraded.Version)
+				default:
+					out.EndOperation(true)
+					reportInstalled(out, upgraded)
+				}
+			}
+			if failed > 0 {
+				return errors.New("failed to upgrade plugins")
+			}
+			return nil
+		},
+	}
+}
+
+func newRemoveCommand(out output.Output, installer *plugin.Installer) *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove NAME",
+		Aliases: []string{"uninstall"},
+		Short:   "Remove an installed plugin",
+		Args:    cobra.MatchAll(cobra.ExactArgs(1), pluginNames),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := installer.Remove(args[0]); err != nil {
+				return err
+			}
+			out.Infof("Removed plugin %s", args[0])
+			return nil
+		},
+	}
+}
+
+// pluginNames checks that the arguments are valid plugin names.
+func pluginNames(cmd *cobra.Command, args []string) error {
+	for _, name := range args {
+		if err := plugin.ValidatePluginName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reportInstalled(out output.Output, installed plugin.InstalledPlugin) {
+	out.Infof("Installed plugin %s %s to %s", installed.Name, installed.Version, installed.Path)
+	if installed.Warning != nil {
+		out.Warn(installed.Warning.Error())
+	}
+}
+
+func orNone(version string) string {
+	if version == "" {
+		return "-"
+	}
+	return version
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugins_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/plugins"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// newIndex writes an index with script plugins of the given versions of the plugin "example" into a new directory
+// and returns the path of the index.
+func newIndex(t *testing.T, versions ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	index := "plugins:\n"
+	for _, version := range versions {
+		data := []byte(fmt.Sprintf(`#!/bin/sh
+echo '{"protocol_version": 1, "name": "dkp-example", "version": "%s", "commands": {"use": "dkp-example"}}'
+`, version))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, version), data, 0o600))
+		sum := sha256.Sum256(data)
+		index += fmt.Sprintf("- name: example\n  version: %s\n  platforms:\n"+
+			"  - os: %s\n    arch: %s\n    url: %s\n    sha256: %s\n",
+			version, runtime.GOOS, runtime.GOARCH, version, hex.EncodeToString(sum[:]))
+	}
+	indexPath := filepath.Join(dir, "index.yaml")
+	require.NoError(t, os.WriteFile(indexPath, []byte(index), 0o600))
+	return indexPath
+}
+
+// execute runs the install commands with the given arguments and returns their output.
+func execute(installer *plugin.Installer, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+	cmd := &cobra.Command{Use: "plugin", SilenceErrors: true, SilenceUsage: true}
+	cmd.AddCommand(plugins.NewInstallCommands(output.NewNonInteractiveShell(out, out, 0), installer)...)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestInstallCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script plugins are not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	installer := plugin.NewInstaller("dkp", dir, newIndex(t, "v1.0.0", "v1.1.0"))
+	installer.HostVersion = "v2.4.0"
+	path := filepath.Join(dir, "dkp-example")
+
+	out, err := execute(installer, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "NAME      INSTALLED   LATEST\nexample   -           v1.1.0\n", out)
+
+	out, err = execute(installer, "install", "example", "--version", "v1.0.0")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Installed plugin example v1.0.0 to "+path)
+	assert.FileExists(t, path)
+
+	out, err = execute(installer, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "NAME      INSTALLED   LATEST\nexample   v1.0.0      v1.1.0\n", out)
+
+	out, err = execute(installer, "upgrade")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Installed plugin example v1.1.0 to "+path)
+
+	out, err = execute(installer, "upgrade", "example")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Plugin example is up to date (v1.1.0)")
+
+	out, err = execute(installer, "uninstall", "example")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Removed plugin example")
+	assert.NoFileExists(t, path)
+
+	out, err = execute(installer, "upgrade", "example")
+	assert.EqualError(t, err, "failed to upgrade plugins")
+	assert.Contains(t, out, "Failed to upgrade plugin example")
+
+	_, err = execute(installer, "install", "example", "--version", "v3.0.0")
+	assert.EqualError(t, err, "plugin example v3.0.0 not found in index")
+}
+
+func TestInstallCommandsValidateNames(t *testing.T) {
+	dir := t.TempDir()
+	installer := plugin.NewInstaller("dkp", dir, filepath.Join(dir, "missing.yaml"))
+
+	tests := map[string]struct {
+		args    []string
+		wantErr string
+	}{
+		"install": {
+			args:    []string{"install", "../example"},
+			wantErr: `invalid plugin name "../example": only lowercase letters, digits, "_" and "-" are allowed`,
+		},
+		"install without name": {
+			args:    []string{"install"},
+			wantErr: "accepts 1 arg(s), received 0",
+		},
+		"upgrade": {
+			args:    []string{"upgrade", "example", "Example"},
+			wantErr: `invalid plugin name "Example": only lowercase letters, digits, "_" and "-" are allowed`,
+		},
+		"remove": {
+			args:    []string{"remove", "../../etc/x"},
+			wantErr: `invalid plugin name "../../etc/x": only lowercase letters, digits, "_" and "-" are allowed`,
+		},
+		"remove several": {
+			args:    []string{"remove", "example", "other"},
+			wantErr: "accepts 1 arg(s), received 2",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := execute(installer, tt.args...)
+			assert.EqualError(t, err, tt.wantErr)
+			// the arguments are rejected before anything is done
+			assert.Empty(t, out)
+		})
+	}
+}
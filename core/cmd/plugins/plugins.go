@@ -0,0 +1,50 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugins
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// NewCommand returns a cobra command for managing external plugins found by the given manager.
+func NewCommand(output io.Writer, manager *plugin.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage plugins",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(newCacheCommand(output, manager))
+	return cmd
+}
+
+func newCacheCommand(output io.Writer, manager *plugin.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of plugin commands",
+		Long: `Manage the cache of plugin commands.
+Plugins are only executed to discover their commands if they changed since they were cached.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached plugin commands",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if manager.Cache == nil {
+				return nil
+			}
+			if err := manager.Cache.Clear(); err != nil {
+				return err
+			}
+			fmt.Fprintf(output, "Removed %s\n", manager.Cache.Dir())
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/history"
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// JournalOptions contains settings for the command journal. The journal is disabled by default.
+type JournalOptions struct {
+	rootCmd *cobra.Command
+	out     io.Writer
+	output  output.Output
+	journal *journal.Journal
+}
+
+func newJournalOptions(rootCmd *cobra.Command, out io.Writer) *JournalOptions {
+	return &JournalOptions{
+		rootCmd: rootCmd,
+		out:     out,
+	}
+}
+
+// Enable records every executed command in the journal stored in the given file and adds a history command to the
+// root command. If path is empty, the journal is stored in the user's state directory.
+//
+// Example:
+//
+//	rootCmd, rootOpts := root.NewCommand(os.Stdout, os.Stderr)
+//	if err := rootOpts.Journal.Enable(""); err != nil {
+//		rootOpts.Output.Error(err, "command journal not available")
+//	}
+func (o *JournalOptions) Enable(path string) error {
+	if o.journal != nil {
+		return nil
+	}
+	if path == "" {
+		var err error
+		path, err = journal.DefaultPath(o.rootCmd.Name())
+		if err != nil {
+			return err
+		}
+	}
+	o.journal = journal.New(path)
+	o.rootCmd.AddCommand(history.NewCommand(o.out, o.journal))
+	return nil
+}
+
+// Journal returns the journal commands are recorded in, nil if the journal is not enabled.
+func (o *JournalOptions) Journal() *journal.Journal {
+	return o.journal
+}
+
+// startRecording wraps the pre-run and run functions of the command about to be executed, so its result is recorded
+// once the run function returns, or the pre-run function fails. The original functions are restored afterwards.
+func (o *JournalOptions) startRecording(cmd *cobra.Command) {
+	if o.journal == nil || journal.IsExcluded(cmd) || (cmd.Run == nil && cmd.RunE == nil) {
+		return
+	}
+
+	start := time.Now()
+	origPreRunE, origRun, origRunE := cmd.PreRunE, cmd.Run, cmd.RunE
+	restore := func() {
+		cmd.PreRunE, cmd.Run, cmd.RunE = origPreRunE, origRun, origRunE
+	}
+	record := func(cmd *cobra.Command, args []string, err error) {
+		if journalErr := o.journal.Append(journal.NewEntry(cmd, args, start, err)); journalErr != nil && o.output != nil {
+			o.output.V(1).Error(journalErr, "failed to record command in journal")
+		}
+	}
+
+	if origPreRunE != nil {
+		cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+			err := origPreRunE(cmd, args)
+			if err != nil {
+				restore()
+				record(cmd, args, err)
+			}
+			return err
+		}
+	}
+	cmd.Run = nil
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		defer restore()
+
+		var err error
+		if origRunE != nil {
+			err = origRunE(cmd, args)
+		} else {
+			origRun(cmd, args)
+		}
+		record(cmd, args, err)
+		return err
+	}
+}
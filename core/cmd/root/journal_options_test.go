@@ -0,0 +1,105 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/root"
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+)
+
+func TestJournal(t *testing.T) {
+	out := bytes.Buffer{}
+	rootCmd, rootOpts := root.NewCommand(&out, io.Discard)
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	assert.Nil(t, rootOpts.Journal.Journal())
+	require.NoError(t, rootOpts.Journal.Enable(filepath.Join(t.TempDir(), "journal.jsonl")))
+	assert.Contains(t, commandNames(rootCmd.Commands(), true), "history")
+
+	succeedCmd := &cobra.Command{Use: "succeed", Run: func(cmd *cobra.Command, args []string) {}}
+	succeedCmd.Flags().String("password", "", "")
+	failCmd := &cobra.Command{Use: "fail", RunE: func(cmd *cobra.Command, args []string) error {
+		return &plugin.ExitError{Plugin: "example", Code: 3}
+	}}
+	preRunFailCmd := &cobra.Command{
+		Use:     "prerun-fail",
+		PreRunE: func(cmd *cobra.Command, args []string) error { return errors.New("invalid") },
+		Run:     func(cmd *cobra.Command, args []string) {},
+	}
+	rootCmd.AddCommand(succeedCmd, failCmd, preRunFailCmd)
+
+	rootCmd.SetArgs([]string{"succeed", "--password=hunter2"})
+	require.NoError(t, rootCmd.Execute())
+	rootCmd.SetArgs([]string{"fail"})
+	require.Error(t, rootCmd.Execute())
+	rootCmd.SetArgs([]string{"prerun-fail"})
+	require.Error(t, rootCmd.Execute())
+	rootCmd.SetArgs([]string{"history"})
+	require.NoError(t, rootCmd.Execute())
+
+	entries, err := rootOpts.Journal.Journal().Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "root.test succeed", entries[0].Command)
+	assert.Equal(t, map[string]string{"password": redact.Placeholder}, entries[0].Flags)
+	assert.Equal(t, 0, entries[0].ExitCode)
+	assert.Equal(t, "root.test fail", entries[1].Command)
+	assert.Equal(t, 3, entries[1].ExitCode)
+	assert.Equal(t, `plugin "example" exited with code 3`, entries[1].Error)
+	assert.Equal(t, "root.test prerun-fail", entries[2].Command)
+	assert.Equal(t, "invalid", entries[2].Error)
+	assert.Contains(t, out.String(), "root.test succeed --password=<redacted>")
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"history", "--failed", "-o", "json"})
+	require.NoError(t, rootCmd.Execute())
+	exported := []journal.Entry{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &exported))
+	require.Len(t, exported, 2)
+	assert.Equal(t, 2, exported[0].ID)
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"history", "show", "1"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, out.String(), "Command:   root.test succeed --password=<redacted>")
+
+	// Original run functions are restored after recording.
+	assert.NotNil(t, succeedCmd.Run)
+	assert.Nil(t, succeedCmd.RunE)
+	assert.NotNil(t, preRunFailCmd.Run)
+	assert.Nil(t, preRunFailCmd.RunE)
+}
+
+func TestSupportBundleIncludesJournal(t *testing.T) {
+	rootCmd, rootOpts := root.NewCommand(io.Discard, io.Discard)
+	assert.NotContains(t, commandNames(rootCmd.Commands(), true), "support-bundle")
+
+	require.NoError(t, rootOpts.Journal.Enable(filepath.Join(t.TempDir(), "journal.jsonl")))
+	rootOpts.SupportBundle.Enable()
+	rootOpts.SupportBundle.Enable()
+	assert.Equal(t, []string{"history", "support-bundle", "version"}, commandNames(rootCmd.Commands(), true))
+
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	rootCmd.SetArgs([]string{"support-bundle", "-f", outputFile})
+	require.NoError(t, rootCmd.Execute())
+	assert.FileExists(t, outputFile)
+
+	entries, err := rootOpts.Journal.Journal().Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "root.test support-bundle", entries[0].Command)
+}
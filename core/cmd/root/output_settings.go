@@ -0,0 +1,114 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jwalton/gchalk"
+	"github.com/spf13/pflag"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+	"github.com/mesosphere/dkp-cli-runtime/core/term"
+)
+
+// Environment variables set by hosts when executing plugins (see PluginOptions), so plugins created with NewCommand
+// produce the same output as the host's built-in commands. Flags passed to a plugin take precedence.
+const (
+	// EnvVerbosity is the output verbosity, like the "--verbose" flag.
+	EnvVerbosity = "DKP_CLI_VERBOSITY"
+	// EnvVmodule is the file-filtered logging configuration, like the "--vmodule" flag.
+	EnvVmodule = "DKP_CLI_VMODULE"
+	// EnvLogFile is the file output is additionally written to, like the "--log-file" flag.
+	EnvLogFile = "DKP_CLI_LOG_FILE"
+	// EnvInteractive is "true" for interactive output (with progress animations and colors), "false" otherwise.
+	EnvInteractive = "DKP_CLI_INTERACTIVE"
+	// EnvColorLevel is the color level of the terminal: 0 (no colors), 1 (16 colors), 2 (256 colors) or 3 (16 million
+	// colors).
+	EnvColorLevel = "DKP_CLI_COLOR_LEVEL"
+)
+
+// outputSettings are the settings output is configured with, derived from flags, environment and terminal.
+type outputSettings struct {
+	verbosity int
+	// verbositySet is true if the verbosity was set explicitly, which enables klog output.
+	verbositySet bool
+	vmodule      string
+	logFile      string
+	interactive  bool
+	colorLevel   gchalk.ColorLevel
+	// eventStream receives output events if this is a plugin executed by a host, see plugin.EnvEventStreamFD.
+	eventStream io.Writer
+}
+
+// resolveOutputSettings determines the output settings from the (already parsed) flags, falling back to the
+// environment variables set by a host and the terminal errOut is connected to.
+func resolveOutputSettings(
+	flags *pflag.FlagSet, errOut io.Writer, lookupEnv func(string) (string, bool),
+) outputSettings {
+	settings := outputSettings{}
+	settings.verbosity, _ = flags.GetInt("verbose")
+	settings.verbositySet = flags.Changed("verbose")
+	settings.vmodule, _ = flags.GetString("vmodule")
+	settings.logFile, _ = flags.GetString("log-file")
+	settings.interactive = term.IsSmartTerminal(errOut)
+	settings.colorLevel = gchalk.Stderr.GetLevel()
+
+	if value, ok := lookupEnv(EnvVerbosity); ok && !settings.verbositySet {
+		if verbosity, err := strconv.Atoi(value); err == nil {
+			settings.verbosity = verbosity
+			settings.verbositySet = true
+		}
+	}
+	if value, ok := lookupEnv(EnvVmodule); ok && !flags.Changed("vmodule") {
+		settings.vmodule = value
+	}
+	if value, ok := lookupEnv(EnvLogFile); ok && !flags.Changed("log-file") {
+		settings.logFile = value
+	}
+	if value, ok := lookupEnv(EnvInteractive); ok {
+		if interactive, err := strconv.ParseBool(value); err == nil {
+			settings.interactive = interactive
+		}
+	}
+	if value, ok := lookupEnv(EnvColorLevel); ok {
+		if level, err := strconv.Atoi(value); err == nil && level >= 0 && level <= int(gchalk.LevelAnsi16m) {
+			settings.colorLevel = gchalk.ColorLevel(level)
+		}
+	}
+	if value, ok := lookupEnv(plugin.EnvEventStreamFD); ok {
+		if fd, err := strconv.Atoi(value); err == nil && fd > 2 {
+			settings.eventStream = os.NewFile(uintptr(fd), "event-stream")
+		}
+	}
+	return settings
+}
+
+// environ returns the environment variables passing the settings on to plugins. The event stream is not passed on,
+// the host of a plugin decides whether to use one.
+func (s outputSettings) environ() []string {
+	env := []string{
+		fmt.Sprintf("%s=%t", EnvInteractive, s.interactive),
+		fmt.Sprintf("%s=%d", EnvColorLevel, s.colorLevel),
+	}
+	if s.verbositySet {
+		env = append(env, fmt.Sprintf("%s=%d", EnvVerbosity, s.verbosity))
+	}
+	if s.vmodule != "" {
+		env = append(env, fmt.Sprintf("%s=%s", EnvVmodule, s.vmodule))
+	}
+	if s.logFile != "" {
+		// plugins may be executed in a different working directory
+		logFile, err := filepath.Abs(s.logFile)
+		if err != nil {
+			logFile = s.logFile
+		}
+		env = append(env, fmt.Sprintf("%s=%s", EnvLogFile, logFile))
+	}
+	return env
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jwalton/gchalk"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func newTestOutputFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntP("verbose", "v", 0, "")
+	flags.String("vmodule", "", "")
+	flags.String("log-file", "", "")
+	require.NoError(t, flags.Parse(args))
+	return flags
+}
+
+func lookupEnvFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestResolveOutputSettings(t *testing.T) {
+	origStderrLevel := gchalk.Stderr.GetLevel()
+
+	// defaults
+	settings := resolveOutputSettings(newTestOutputFlags(t), io.Discard, lookupEnvFrom(nil))
+	assert.Equal(t, 0, settings.verbosity)
+	assert.False(t, settings.verbositySet)
+	assert.False(t, settings.interactive)
+	assert.Empty(t, settings.logFile)
+
+	// environment set by a host
+	env := map[string]string{
+		EnvVerbosity:   "3",
+		EnvVmodule:     "foo=2",
+		EnvLogFile:     "/tmp/host.log",
+		EnvInteractive: "true",
+		EnvColorLevel:  "2",
+	}
+	settings = resolveOutputSettings(newTestOutputFlags(t), io.Discard, lookupEnvFrom(env))
+	assert.Equal(t, outputSettings{
+		verbosity:    3,
+		verbositySet: true,
+		vmodule:      "foo=2",
+		logFile:      "/tmp/host.log",
+		interactive:  true,
+		colorLevel:   gchalk.LevelAnsi256,
+	}, settings)
+	// the color level is applied to the output, not globally
+	assert.Equal(t, origStderrLevel, gchalk.Stderr.GetLevel())
+
+	// flags take precedence
+	settings = resolveOutputSettings(
+		newTestOutputFlags(t, "-v", "1", "--log-file", "plugin.log"), io.Discard, lookupEnvFrom(env),
+	)
+	assert.Equal(t, 1, settings.verbosity)
+	assert.Equal(t, "plugin.log", settings.logFile)
+
+	// invalid values are ignored
+	settings = resolveOutputSettings(newTestOutputFlags(t), io.Discard, lookupEnvFrom(map[string]string{
+		EnvVerbosity:   "loud",
+		EnvInteractive: "maybe",
+	}))
+	assert.False(t, settings.verbositySet)
+	assert.False(t, settings.interactive)
+}
+
+func TestOutputSettingsEnviron(t *testing.T) {
+	settings := outputSettings{interactive: true, colorLevel: gchalk.LevelBasic}
+	assert.Equal(t, []string{EnvInteractive + "=true", EnvColorLevel + "=1"}, settings.environ())
+
+	settings = outputSettings{verbosity: 2, verbositySet: true, vmodule: "foo=2", logFile: "/tmp/host.log"}
+	assert.Equal(t, []string{
+		EnvInteractive + "=false",
+		EnvColorLevel + "=0",
+		EnvVerbosity + "=2",
+		EnvVmodule + "=foo=2",
+		EnvLogFile + "=/tmp/host.log",
+	}, settings.environ())
+
+	// settings are passed on unchanged
+	env := map[string]string{}
+	for _, keyValue := range settings.environ() {
+		key, value, _ := strings.Cut(keyValue, "=")
+		env[key] = value
+	}
+	assert.Equal(t, settings, resolveOutputSettings(newTestOutputFlags(t), io.Discard, lookupEnvFrom(env)))
+}
+
+func TestNewCommandConsumesEventStreamFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+	t.Setenv(plugin.EnvEventStreamFD, strconv.Itoa(int(w.Fd())))
+
+	_, rootOptions := NewCommand(io.Discard, io.Discard)
+	rootOptions.Output.Info("event")
+
+	_, ok := os.LookupEnv(plugin.EnvEventStreamFD)
+	assert.False(t, ok)
+}
+
+func TestConfigureOutputLogFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "output.log")
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+
+	o, f := configureOutput(&out, &errOut, outputSettings{verbosity: 1, logFile: logFile})
+	require.NotNil(t, f)
+	defer f.Close()
+	o.Info("info")
+	o.V(1).Info("verbose")
+	o.V(2).Info("very verbose")
+	o.Result("result")
+
+	assert.Equal(t, "result\n", out.String())
+	assert.Contains(t, errOut.String(), "INF verbose")
+
+	logged, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(logged), "INF info\n")
+	assert.Contains(t, string(logged), "INF verbose\n")
+	assert.NotContains(t, string(logged), "very verbose")
+	assert.NotContains(t, string(logged), "result")
+}
+
+func TestRootOptionsCloseLogFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "output.log")
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+	os.Args = []string{"dkp", "--log-file", logFile, "version", "--unknown"}
+
+	rootCmd, rootOptions := NewCommand(io.Discard, io.Discard)
+	rootCmd.SetArgs(os.Args[1:])
+	rootCmd.SilenceErrors = true
+	rootOptions.Output.Info("before")
+	// the log file stays open when the command fails, so the caller can log the error
+	err := rootCmd.Execute()
+	require.Error(t, err)
+	rootOptions.Output.Error(err, "failed")
+	require.NoError(t, rootOptions.Close())
+	require.NoError(t, rootOptions.Close())
+	rootOptions.Output.Info("after")
+
+	logged, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(logged), "before")
+	assert.Contains(t, string(logged), "failed")
+	assert.NotContains(t, string(logged), "after")
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/plugins"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// PluginOptions contains settings for external plugins. Plugins are disabled by default.
+type PluginOptions struct {
+	rootCmd *cobra.Command
+	out     io.Writer
+	output  output.Output
+	// env passes the output settings on to plugins
+	env     []string
+	manager *plugin.Manager
+	// pluginCmd is the command managing plugins
+	pluginCmd *cobra.Command
+	installer *plugin.Installer
+}
+
+func newPluginOptions(rootCmd *cobra.Command, out io.Writer) *PluginOptions {
+	return &PluginOptions{
+		rootCmd: rootCmd,
+		out:     out,
+	}
+}
+
+// Enable finds plugin executables named "<prefix>-<name>" in dirs and PATH and adds their commands to the root
+// command (see plugin.Manager). Plugins that cannot be discovered or whose commands conflict with existing commands
+// are reported with verbosity 1, incompatible plugins (see plugin.Spec.CheckCompatibility) are reported as warnings.
+// Discovered commands are cached in the user's cache directory, shell completion only uses cached commands. Plugins
+// are executed with environment variables passing on the output settings (see EnvVerbosity etc.) and plugins created
+// with NewCommand send their output to the host to be rendered (see plugin.CapabilityEventStream), so their output
+// matches the output of built-in commands. A plugin command is added to the root command to manage plugins, e.g. to
+// clear the cache.
+//
+// Example:
+//
+//	rootCmd, rootOpts := root.NewCommand(os.Stdout, os.Stderr)
+//	rootOpts.Plugins.Enable("dkp", filepath.Join(homeDir, ".dkp", "plugins"))
+func (o *PluginOptions) Enable(prefix string, dirs ...string) {
+	if o.manager != nil {
+		return
+	}
+	o.manager = plugin.NewManager(prefix, dirs...)
+	o.manager.Env = o.env
+	o.manager.Output = o.output
+	if cacheDir, err := plugin.DefaultCacheDir(o.rootCmd.Name()); err == nil {
+		o.manager.Cache = plugin.NewCache(cacheDir)
+	} else {
+		o.output.V(1).Infof("plugin cache not available: %v", err)
+	}
+	// completion must be fast, so plugins are not executed
+	o.manager.CacheOnly = isCompletionRequest(os.Args)
+	o.pluginCmd = plugins.NewCommand(o.out, o.manager)
+	o.rootCmd.AddCommand(o.pluginCmd)
+
+	plugins, errs := o.manager.Discover(context.Background())
+	errs = append(errs, o.manager.Mount(o.rootCmd, plugins...)...)
+	for _, err := range errs {
+		var incompatibleErr *plugin.IncompatibleError
+		var compatibilityWarning *plugin.CompatibilityWarning
+		if errors.As(err, &incompatibleErr) || errors.As(err, &compatibilityWarning) {
+			o.output.Warn(err.Error())
+			continue
+		}
+		o.output.V(1).Info(err.Error())
+	}
+}
+
+// EnableInstall adds commands to the plugin command that install, list, upgrade and remove plugins listed in the
+// index, an HTTP(S) URL or a file (see plugin.Installer). Plugins are installed into the first directory passed to
+// Enable, which must be called first.
+//
+// Example:
+//
+//	rootOpts.Plugins.Enable("dkp", filepath.Join(homeDir, ".dkp", "plugins"))
+//	rootOpts.Plugins.EnableInstall("https://downloads.example.com/dkp/plugins.yaml")
+func (o *PluginOptions) EnableInstall(index string) {
+	if o.manager == nil || o.installer != nil || len(o.manager.Dirs) == 0 {
+		return
+	}
+	o.installer = plugin.NewInstaller(o.manager.Prefix, o.manager.Dirs[0], index)
+	o.pluginCmd.AddCommand(plugins.NewInstallCommands(o.output, o.installer)...)
+}
+
+// Installer returns the plugin installer, nil if installing plugins is not enabled.
+func (o *PluginOptions) Installer() *plugin.Installer {
+	return o.installer
+}
+
+// Manager returns the plugin manager, nil if plugins are not enabled.
+func (o *PluginOptions) Manager() *plugin.Manager {
+	return o.manager
+}
+
+func isCompletionRequest(args []string) bool {
+	return len(args) > 1 &&
+		(args[1] == cobra.ShellCompRequestCmd || args[1] == cobra.ShellCompNoDescRequestCmd)
+}
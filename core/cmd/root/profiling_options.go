@@ -4,67 +4,150 @@
 package root
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
+const (
+	profileNone  = "none"
+	profileCPU   = "cpu"
+	profileTrace = "trace"
+	profileHeap  = "heap"
+)
+
 // ProfilingOptions contains settings for profiling.
 type ProfilingOptions struct {
-	profileName   string
-	profileOutput string
+	profileNames         []string
+	profileOutput        string
+	profileHTTPAddress   string
+	heapSnapshotInterval time.Duration
+
+	// below are set while profiling is running
+	openFiles    []*os.File
+	httpServer   *http.Server
+	stopSnapshot chan struct{}
+	snapshotDone sync.WaitGroup
+	flushOnce    sync.Once
+	flushErr     error
 }
 
 // NewProfilingOptions initializes ProfilingOptions with defaults.
 func NewProfilingOptions() *ProfilingOptions {
 	return &ProfilingOptions{
-		profileName:   "none",
+		profileNames:  []string{profileNone},
 		profileOutput: "profile.pprof",
 	}
 }
 
 // AddFlags adds flags for setting profiling options to the provided FlagSet.
 func (o *ProfilingOptions) AddFlags(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(
-		&o.profileName, "profile", o.profileName,
-		"Name of profile to capture. One of (none|cpu|goroutine|threadcreate|heap|allocs|block|mutex)",
+	flagSet.StringSliceVar(
+		&o.profileNames, "profile", o.profileNames,
+		"Comma-separated names of profiles to capture. "+
+			"Any of (none|cpu|trace|goroutine|threadcreate|heap|allocs|block|mutex)",
 	)
 	_ = flagSet.MarkHidden("profile")
-	flagSet.StringVar(&o.profileOutput, "profile-output", o.profileOutput, "Name of the file to write the profile to")
+	flagSet.StringVar(
+		&o.profileOutput, "profile-output", o.profileOutput,
+		"Name of the file to write the profile to. If multiple profiles are captured, "+
+			"the profile name is added to the file name, e.g. profile-cpu.pprof",
+	)
 	_ = flagSet.MarkHidden("profile-output")
+	flagSet.StringVar(
+		&o.profileHTTPAddress, "profile-http", o.profileHTTPAddress,
+		"Address to serve live pprof endpoints on while the command runs, e.g. localhost:6060",
+	)
+	_ = flagSet.MarkHidden("profile-http")
+	flagSet.DurationVar(
+		&o.heapSnapshotInterval, "profile-heap-interval", o.heapSnapshotInterval,
+		"Interval in which to write heap snapshots while the command runs, e.g. 5m. Disabled if 0",
+	)
+	_ = flagSet.MarkHidden("profile-heap-interval")
 }
 
-// InitProfiling starts profiling.
-func (o *ProfilingOptions) InitProfiling() error {
-	switch o.profileName {
-	case "none":
+// InitProfiling starts profiling. If a profile cannot be started, the profiles started before are stopped and their
+// files are closed.
+func (o *ProfilingOptions) InitProfiling() (err error) {
+	profiles, err := o.profiles()
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 && o.profileHTTPAddress == "" && o.heapSnapshotInterval <= 0 {
 		return nil
-	case "cpu":
-		f, err := os.Create(o.profileOutput)
-		if err != nil {
-			return err
+	}
+
+	// stops the profiles started so far, in reverse order
+	var stops []func()
+	defer func() {
+		if err == nil {
+			return
 		}
-		err = pprof.StartCPUProfile(f)
-		if err != nil {
-			return err
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+		for _, f := range o.openFiles {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
 		}
-	// Block and mutex profiles need a call to Set{Block,Mutex}ProfileRate to
-	// output anything. We choose to sample all events.
-	case "block":
-		runtime.SetBlockProfileRate(1)
-	case "mutex":
-		runtime.SetMutexProfileFraction(1)
-	default:
-		// Check the profile name is valid.
-		if profile := pprof.Lookup(o.profileName); profile == nil {
-			return fmt.Errorf("unknown profile '%s'", o.profileName)
+		o.openFiles = nil
+	}()
+
+	for _, name := range profiles {
+		switch name {
+		case profileCPU:
+			f, err := o.create(o.outputFile(name))
+			if err != nil {
+				return err
+			}
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return err
+			}
+			stops = append(stops, pprof.StopCPUProfile)
+		case profileTrace:
+			f, err := o.create(o.outputFile(name))
+			if err != nil {
+				return err
+			}
+			if err := trace.Start(f); err != nil {
+				return err
+			}
+			stops = append(stops, trace.Stop)
+		// Block and mutex profiles need a call to Set{Block,Mutex}ProfileRate to
+		// output anything. We choose to sample all events.
+		case "block":
+			runtime.SetBlockProfileRate(1)
+			stops = append(stops, func() { runtime.SetBlockProfileRate(0) })
+		case "mutex":
+			previous := runtime.SetMutexProfileFraction(1)
+			stops = append(stops, func() { runtime.SetMutexProfileFraction(previous) })
 		}
 	}
 
+	if o.profileHTTPAddress != "" {
+		if err := o.startHTTPServer(); err != nil {
+			return err
+		}
+	}
+
+	if o.heapSnapshotInterval > 0 {
+		o.startHeapSnapshots()
+	}
+
 	// If the command is interrupted before the end (ctrl-c), flush the
 	// profiling files
 	c := make(chan os.Signal, 1)
@@ -78,27 +161,241 @@ func (o *ProfilingOptions) InitProfiling() error {
 	return nil
 }
 
-// FlushProfiling stops profiling and writes remaining unwritten data.
+// FlushProfiling stops profiling and writes remaining unwritten data. Subsequent calls have no effect.
 func (o *ProfilingOptions) FlushProfiling() error {
-	switch o.profileName {
-	case "none":
+	o.flushOnce.Do(func() {
+		o.flushErr = o.flushProfiling()
+	})
+	return o.flushErr
+}
+
+func (o *ProfilingOptions) flushProfiling() error {
+	profiles, err := o.profiles()
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if o.stopSnapshot != nil {
+		close(o.stopSnapshot)
+		o.snapshotDone.Wait()
+	}
+	if o.httpServer != nil {
+		if err := o.httpServer.Shutdown(context.Background()); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	for _, name := range profiles {
+		switch name {
+		case profileCPU:
+			pprof.StopCPUProfile()
+		case profileTrace:
+			trace.Stop()
+		default:
+			if err := o.writeProfile(name, o.outputFile(name)); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	for _, f := range o.openFiles {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	o.openFiles = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to flush profiling data: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// profiles returns the validated list of requested profiles, without "none" and duplicates.
+func (o *ProfilingOptions) profiles() ([]string, error) {
+	profiles := make([]string, 0, len(o.profileNames))
+	seen := map[string]bool{}
+	for _, name := range o.profileNames {
+		name = strings.TrimSpace(name)
+		if name == "" || name == profileNone || seen[name] {
+			continue
+		}
+		switch name {
+		case profileCPU, profileTrace:
+		default:
+			// Check the profile name is valid.
+			if profile := pprof.Lookup(name); profile == nil {
+				return nil, fmt.Errorf("unknown profile '%s'", name)
+			}
+		}
+		seen[name] = true
+		profiles = append(profiles, name)
+	}
+	return profiles, nil
+}
+
+// outputFile returns the file a profile is written to. The configured output file is used as is if only a single
+// profile is captured, otherwise the profile name is added in front of the file extension.
+func (o *ProfilingOptions) outputFile(name string) string {
+	profiles, _ := o.profiles()
+	if len(profiles) <= 1 {
+		return o.profileOutput
+	}
+	return withFileSuffix(o.profileOutput, name)
+}
+
+func withFileSuffix(file, suffix string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + suffix + ext
+}
+
+func (o *ProfilingOptions) create(file string) (*os.File, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	o.openFiles = append(o.openFiles, f)
+	return f, nil
+}
+
+func (o *ProfilingOptions) writeProfile(name, file string) error {
+	profile := pprof.Lookup(name)
+	if profile == nil {
 		return nil
-	case "cpu":
-		pprof.StopCPUProfile()
-	case "heap":
+	}
+	if name == profileHeap {
 		runtime.GC()
-		fallthrough
-	default:
-		profile := pprof.Lookup(o.profileName)
-		if profile == nil {
-			return nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return profile.WriteTo(f, 0)
+}
+
+func (o *ProfilingOptions) startHTTPServer() error {
+	listener, err := net.Listen("tcp", o.profileHTTPAddress)
+	if err != nil {
+		return fmt.Errorf("failed to serve profiles on %s: %w", o.profileHTTPAddress, err)
+	}
+
+	o.httpServer = &http.Server{
+		Handler:           newPprofHandler(),
+		ReadHeaderTimeout: 10 * time.Second, //nolint:gomnd // Reasonable timeout for a debug endpoint.
+	}
+	go func() {
+		if err := o.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "pprof server stopped: %v\n", err)
 		}
-		f, err := os.Create(o.profileOutput)
-		if err != nil {
-			return err
+	}()
+	return nil
+}
+
+// newPprofHandler serves the endpoints of net/http/pprof used by "go tool pprof" and "go tool trace". They are
+// implemented with runtime/pprof, because importing net/http/pprof registers them on http.DefaultServeMux of the host.
+func newPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", servePprofProfile)
+	mux.HandleFunc("/debug/pprof/cmdline", servePprofCmdline)
+	mux.HandleFunc("/debug/pprof/profile", servePprofCPUProfile)
+	mux.HandleFunc("/debug/pprof/trace", servePprofTrace)
+	return mux
+}
+
+// servePprofProfile serves the named profile, e.g. /debug/pprof/heap?debug=1, or lists the profiles.
+func servePprofProfile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+	if name == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, profile := range pprof.Profiles() {
+			fmt.Fprintf(w, "%s\t%d\n", profile.Name(), profile.Count())
 		}
-		_ = profile.WriteTo(f, 0)
+		return
 	}
 
-	return nil
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		http.Error(w, fmt.Sprintf("unknown profile %q", name), http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(r.FormValue("debug"))
+	if debug != 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+	if name == profileHeap && r.FormValue("gc") != "" {
+		runtime.GC()
+	}
+	_ = profile.WriteTo(w, debug)
+}
+
+func servePprofCmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+}
+
+// servePprofCPUProfile serves a CPU profile of the duration given by the "seconds" parameter, 30s by default.
+func servePprofCPUProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// e.g. with --profile=cpu
+		http.Error(w, fmt.Sprintf("cannot start CPU profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sleepForRequest(r, 30*time.Second) //nolint:gomnd // Default of net/http/pprof.
+	pprof.StopCPUProfile()
+}
+
+// servePprofTrace serves an execution trace of the duration given by the "seconds" parameter, 1s by default.
+func servePprofTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := trace.Start(w); err != nil {
+		// e.g. with --profile=trace
+		http.Error(w, fmt.Sprintf("cannot start trace: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sleepForRequest(r, time.Second)
+	trace.Stop()
+}
+
+// sleepForRequest waits for the duration given by the "seconds" parameter of r (defaultDuration if there is none),
+// or until the request is cancelled.
+func sleepForRequest(r *http.Request, defaultDuration time.Duration) {
+	duration := defaultDuration
+	if seconds, err := strconv.ParseFloat(r.FormValue("seconds"), 64); err == nil && seconds > 0 {
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// startHeapSnapshots periodically writes heap profiles to numbered files, e.g. profile-heap-0001.pprof. This helps
+// finding memory leaks that only show up in long-running commands.
+func (o *ProfilingOptions) startHeapSnapshots() {
+	o.stopSnapshot = make(chan struct{})
+	o.snapshotDone.Add(1)
+	go func() {
+		defer o.snapshotDone.Done()
+		ticker := time.NewTicker(o.heapSnapshotInterval)
+		defer ticker.Stop()
+		for i := 1; ; i++ {
+			select {
+			case <-o.stopSnapshot:
+				return
+			case <-ticker.C:
+				file := withFileSuffix(o.profileOutput, fmt.Sprintf("%s-%04d", profileHeap, i))
+				if err := o.writeProfile(profileHeap, file); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write heap snapshot: %v\n", err)
+				}
+			}
+		}
+	}()
 }
@@ -0,0 +1,150 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"runtime/trace"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProfilingOptions(t *testing.T, args ...string) *ProfilingOptions {
+	t.Helper()
+	o := NewProfilingOptions()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.AddFlags(flags)
+	require.NoError(t, flags.Parse(args))
+	return o
+}
+
+func TestProfilingSingleProfile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "profile.pprof")
+	o := newTestProfilingOptions(t, "--profile=heap", "--profile-output="+output)
+
+	require.NoError(t, o.InitProfiling())
+	require.NoError(t, o.FlushProfiling())
+
+	assert.FileExists(t, output)
+}
+
+func TestProfilingMultipleProfiles(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "profile.pprof")
+	o := newTestProfilingOptions(t, "--profile=cpu,trace,heap,goroutine", "--profile-output="+output)
+
+	require.NoError(t, o.InitProfiling())
+	require.NoError(t, o.FlushProfiling())
+	// flushing twice must be harmless
+	require.NoError(t, o.FlushProfiling())
+
+	for _, name := range []string{"cpu", "trace", "heap", "goroutine"} {
+		file := filepath.Join(dir, "profile-"+name+".pprof")
+		assert.FileExists(t, file)
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Size(), name)
+	}
+	assert.NoFileExists(t, output)
+}
+
+func TestProfilingUnknownProfile(t *testing.T) {
+	o := newTestProfilingOptions(t, "--profile=cpu,unknown")
+	assert.EqualError(t, o.InitProfiling(), "unknown profile 'unknown'")
+}
+
+func TestProfilingStopsStartedProfilesOnError(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	dir := t.TempDir()
+	o := newTestProfilingOptions(t,
+		"--profile=cpu,trace", "--profile-output="+filepath.Join(dir, "profile.pprof"),
+		"--profile-http="+listener.Addr().String(),
+	)
+	require.Error(t, o.InitProfiling())
+
+	// the profiles can be started again and their files are removed
+	require.NoError(t, pprof.StartCPUProfile(io.Discard))
+	pprof.StopCPUProfile()
+	require.NoError(t, trace.Start(io.Discard))
+	trace.Stop()
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestProfilingNone(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestProfilingOptions(t, "--profile-output="+filepath.Join(dir, "profile.pprof"))
+
+	require.NoError(t, o.InitProfiling())
+	require.NoError(t, o.FlushProfiling())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestProfilingHTTPServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	o := newTestProfilingOptions(t, "--profile-http="+address)
+	require.NoError(t, o.InitProfiling())
+
+	resp, err := http.Get("http://" + address + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "goroutine profile")
+
+	resp, err = http.Get("http://" + address + "/debug/pprof/trace?seconds=0.1")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body)
+
+	// the endpoints are only served by the profiling server
+	_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Empty(t, pattern)
+
+	require.NoError(t, o.FlushProfiling())
+	_, err = http.Get("http://" + address + "/debug/pprof/")
+	assert.Error(t, err)
+}
+
+func TestProfilingHeapSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestProfilingOptions(t,
+		"--profile-heap-interval=10ms",
+		"--profile-output="+filepath.Join(dir, "profile.pprof"),
+	)
+
+	require.NoError(t, o.InitProfiling())
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "profile-heap-0002.pprof"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, o.FlushProfiling())
+
+	assert.FileExists(t, filepath.Join(dir, "profile-heap-0001.pprof"))
+}
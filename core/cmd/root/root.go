@@ -18,25 +18,48 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/mesosphere/dkp-cli-runtime/core/cmd/help"
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/symlinks"
 	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
-	"github.com/mesosphere/dkp-cli-runtime/core/term"
 )
 
 // RootOptions contains options configured in the root command.
 type RootOptions struct {
-	Profiling *ProfilingOptions
-	Output    output.Output
+	Profiling     *ProfilingOptions
+	Journal       *JournalOptions
+	SupportBundle *SupportBundleOptions
+	Plugins       *PluginOptions
+	Discovery     *plugin.DiscoveryOptions
+	Output        output.Output
+
+	logFile *os.File
+}
+
+// Close closes the file opened for the "--log-file" flag, if any. Call it after executing the root command, also if
+// the execution failed. Output is no longer written to the log file afterwards.
+func (o *RootOptions) Close() error {
+	if o.logFile == nil {
+		return nil
+	}
+	err := o.logFile.Close()
+	o.logFile = nil
+	return err
 }
 
 // NewCommand creates a root command with useful built-in features like:
 // - profiling
 // - version command with different output formats
 // - help command with different output formats
-// - command discovery for use as a CLI plugin.
+// - command discovery for use as a CLI plugin
+// - a command comparing command specs to detect breaking changes (see plugin.DiffSpecs)
+// - a command creating symlinks for subcommands (see symlinks.Create)
+// - an opt-in command journal with a history command (see JournalOptions.Enable)
+// - an opt-in support-bundle command (see SupportBundleOptions.Enable)
+// - opt-in external plugins (see PluginOptions.Enable).
 func NewCommand(out, errOut io.Writer) (*cobra.Command, *RootOptions) {
 	profilingOpts := NewProfilingOptions()
+	var journalOpts *JournalOptions
 
 	rootCmd := &cobra.Command{
 		Use:          filepath.Base(os.Args[0]),
@@ -47,6 +70,7 @@ func NewCommand(out, errOut io.Writer) (*cobra.Command, *RootOptions) {
 			if err := profilingOpts.InitProfiling(); err != nil {
 				return err
 			}
+			journalOpts.startRecording(cmd)
 			return nil
 		},
 		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -54,17 +78,25 @@ func NewCommand(out, errOut io.Writer) (*cobra.Command, *RootOptions) {
 		},
 	}
 
+	journalOpts = newJournalOptions(rootCmd, out)
+	supportBundleOpts := newSupportBundleOptions(rootCmd, journalOpts)
+	pluginOpts := newPluginOptions(rootCmd, out)
+
 	profilingOpts.AddFlags(rootCmd.PersistentFlags())
-	outputVerbosity := 0
-	klogVmodule := ""
-	rootCmd.PersistentFlags().IntVarP(&outputVerbosity, "verbose", "v", 0, "Output verbosity")
-	rootCmd.PersistentFlags().StringVar(&klogVmodule, "vmodule", "",
+	rootCmd.PersistentFlags().IntP("verbose", "v", 0, "Output verbosity")
+	rootCmd.PersistentFlags().String("vmodule", "",
 		"Comma-separated list of pattern=N settings for file-filtered logging")
 	rootCmd.PersistentFlags().MarkHidden("vmodule") //nolint:errcheck // flag just created, guaranteed to succeed
+	rootCmd.PersistentFlags().String("log-file", "", "Additionally write output to this file")
 	ensureTitleCaseForHelpFlagUsage(rootCmd)
 
 	rootCmd.AddCommand(version.NewCommand(out))
-	rootCmd.AddCommand(plugin.NewDiscoveryCommand(out, rootCmd))
+	discoveryOpts := &plugin.DiscoveryOptions{
+		Capabilities: plugin.Capabilities{plugin.CapabilityEventStream},
+	}
+	rootCmd.AddCommand(plugin.NewDiscoveryCommandWithOptions(out, rootCmd, discoveryOpts))
+	rootCmd.AddCommand(plugin.NewSpecDiffCommand(out))
+	rootCmd.AddCommand(symlinks.NewCommand(out, rootCmd))
 	rootCmd.SetHelpCommand(help.NewHelpCommandWrapper(rootCmd))
 
 	// Make sure flags are parsed, ignoring unknown flags at this stage. This ensures that the
@@ -75,39 +107,73 @@ func NewCommand(out, errOut io.Writer) (*cobra.Command, *RootOptions) {
 	_ = rootCmd.PersistentFlags().Parse(os.Args)
 	rootCmd.PersistentFlags().ParseErrorsWhitelist = origParseErrorsWhitelist
 
-	verbosityFlagSet := rootCmd.PersistentFlags().Changed("verbose")
+	settings := resolveOutputSettings(rootCmd.PersistentFlags(), errOut, os.LookupEnv)
+	// the event stream belongs to this process, processes executed by it must not write to the descriptor
+	_ = os.Unsetenv(plugin.EnvEventStreamFD)
 
 	rootOpts := &RootOptions{
-		Profiling: profilingOpts,
-		Output:    configureOutput(out, errOut, outputVerbosity, verbosityFlagSet, klogVmodule),
+		Profiling:     profilingOpts,
+		Journal:       journalOpts,
+		SupportBundle: supportBundleOpts,
+		Plugins:       pluginOpts,
+		Discovery:     discoveryOpts,
 	}
+	rootOpts.Output, rootOpts.logFile = configureOutput(out, errOut, settings)
+	journalOpts.output = rootOpts.Output
+	supportBundleOpts.output = rootOpts.Output
+	pluginOpts.output = rootOpts.Output
+	pluginOpts.env = settings.environ()
 	return rootCmd, rootOpts
 }
 
-func configureOutput(out, errOut io.Writer, verbosity int, verbosityFlagSet bool, klogVmodule string) output.Output {
-	o := newOutput(out, errOut, verbosity)
+// configureOutput returns the output configured with settings and the log file it writes to, if any. The log file is
+// closed by RootOptions.Close.
+func configureOutput(out, errOut io.Writer, settings outputSettings) (output.Output, *os.File) {
+	var logFile *os.File
+	var logFileErr error
+	// the host of a plugin writes the plugin's events to the log file
+	if settings.logFile != "" && settings.eventStream == nil {
+		logFile, logFileErr = os.OpenFile(settings.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if logFileErr != nil {
+			logFile = nil
+		}
+	}
+
+	o := newOutput(out, errOut, logFile, settings.verbosity, settings)
+	if logFileErr != nil {
+		o.Warnf("Cannot write to log file: %v", logFileErr)
+	}
 
 	// send output of standard logger to Info, verbosity 1
 	log.SetFlags(0)
 	log.SetOutput(o.V(1).InfoWriter())
 
 	// send klog logs to output if verbosity flag is set
-	if verbosityFlagSet || klogVmodule != "" {
-		o := newOutput(out, errOut, math.MaxInt)
-		configureKlog(o, verbosity, klogVmodule)
+	if settings.verbositySet || settings.vmodule != "" {
+		o := newOutput(out, errOut, logFile, math.MaxInt, settings)
+		configureKlog(o, settings.verbosity, settings.vmodule)
 	} else {
 		klog.SetLogger(logr.Discard())
 	}
 
-	return o
+	return o, logFile
 }
 
-func newOutput(out, errOut io.Writer, verbosity int) output.Output {
-	if term.IsSmartTerminal(errOut) {
-		return output.NewInteractiveShell(out, errOut, verbosity)
-	} else {
-		return output.NewNonInteractiveShell(out, errOut, verbosity)
+func newOutput(out, errOut io.Writer, logFile *os.File, verbosity int, settings outputSettings) output.Output {
+	var o output.Output
+	switch {
+	case settings.eventStream != nil:
+		o = output.NewEventStreamOutput(settings.eventStream, verbosity)
+	case settings.interactive:
+		o = output.NewInteractiveShellWithColorLevel(out, errOut, verbosity, settings.colorLevel)
+	default:
+		o = output.NewNonInteractiveShell(out, errOut, verbosity)
+	}
+	if logFile != nil {
+		// results are not logged, they are usually data processed by other tools
+		o = output.NewTeeOutput(o, output.NewNonInteractiveShell(io.Discard, logFile, verbosity))
 	}
+	return o
 }
 
 func configureKlog(o output.Output, verbosity int, vModule string) {
@@ -22,15 +22,20 @@ func TestNewCommand(t *testing.T) {
 	rootCmd, rootOptions := root.NewCommand(io.Discard, io.Discard)
 
 	// all
-	assert.ElementsMatch([]string{"version", "_plugin_commands"}, commandNames(rootCmd.Commands(), false))
 	assert.ElementsMatch(
-		[]string{"profile", "profile-output", "verbose", "v", "vmodule"},
+		[]string{"version", "_plugin_commands", "_plugin_spec_diff", "_symlinks"},
+		commandNames(rootCmd.Commands(), false),
+	)
+	assert.ElementsMatch(
+		[]string{
+			"profile", "profile-output", "profile-http", "profile-heap-interval", "verbose", "v", "vmodule", "log-file",
+		},
 		flagNames(rootCmd.PersistentFlags(), false),
 	)
 
 	// visible
 	assert.ElementsMatch([]string{"version"}, commandNames(rootCmd.Commands(), true))
-	assert.ElementsMatch([]string{"verbose", "v"}, flagNames(rootCmd.PersistentFlags(), true))
+	assert.ElementsMatch([]string{"verbose", "v", "log-file"}, flagNames(rootCmd.PersistentFlags(), true))
 
 	assert.NotNil(rootOptions.Profiling)
 	assert.NotNil(rootOptions.Output)
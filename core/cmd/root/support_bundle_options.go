@@ -0,0 +1,59 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package root
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/supportbundle"
+)
+
+const journalEntriesInSupportBundle = 100
+
+// SupportBundleOptions contains settings for the support bundle command. The command is disabled by default.
+type SupportBundleOptions struct {
+	rootCmd  *cobra.Command
+	journal  *JournalOptions
+	output   output.Output
+	registry *supportbundle.Registry
+	enabled  bool
+}
+
+func newSupportBundleOptions(rootCmd *cobra.Command, journal *JournalOptions) *SupportBundleOptions {
+	return &SupportBundleOptions{
+		rootCmd:  rootCmd,
+		journal:  journal,
+		registry: supportbundle.NewRegistry(supportbundle.DefaultCollectors(rootCmd)...),
+	}
+}
+
+// Enable adds a support-bundle command to the root command. The bundle contains the default collectors (see
+// supportbundle.DefaultCollectors), the latest journal entries if the journal is enabled and all collectors
+// registered with Register.
+//
+// Example:
+//
+//	rootCmd, rootOpts := root.NewCommand(os.Stdout, os.Stderr)
+//	rootOpts.SupportBundle.Register(
+//		supportbundle.VersionCollector(getVersions),
+//		supportbundle.LogFilesCollector(filepath.Join(logDir, "*.log"), 5),
+//	)
+//	rootOpts.SupportBundle.Enable()
+func (o *SupportBundleOptions) Enable() {
+	if o.enabled {
+		return
+	}
+	o.enabled = true
+	if j := o.journal.Journal(); j != nil {
+		o.registry.Register(supportbundle.JournalCollector(j, journalEntriesInSupportBundle))
+	}
+	o.rootCmd.AddCommand(supportbundle.NewCommand(o.output, o.registry))
+}
+
+// Register adds collectors to the support bundle. A collector replaces a default collector with the same name, e.g.
+// supportbundle.VersionCollector replaces the default version collector.
+func (o *SupportBundleOptions) Register(collectors ...supportbundle.Collector) {
+	o.registry.Register(collectors...)
+}
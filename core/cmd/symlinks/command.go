@@ -0,0 +1,52 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package symlinks
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+)
+
+// CommandName is the name of the hidden command creating symlinks.
+const CommandName = "_symlinks"
+
+// NewCommand returns a hidden cobra command creating or removing symlinks for the commands of root in a directory, e.g.
+// when packaging the executable.
+func NewCommand(output io.Writer, root *cobra.Command) *cobra.Command {
+	opts := Options{}
+	remove := false
+	cmd := &cobra.Command{
+		Use:   CommandName + " DIR",
+		Short: "Create symlinks for commands",
+		Long: `Create symlinks for commands in a directory, e.g. "dkp-create-cluster" for "dkp create cluster".
+The executable handles the symlinks if its main function calls symlinkexechandler.HandleExec.`,
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			"exclude-from-dkp-cli":    "true",
+			journal.ExcludeAnnotation: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apply, verb := Create, "Created"
+			if remove {
+				apply, verb = Remove, "Removed"
+			}
+			links, err := apply(root, args[0], opts)
+			for _, link := range links {
+				fmt.Fprintf(output, "%s %s\n", verb, link.Name)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&opts.Prefix, "prefix", "", "Prefix of the symlinks, the name of the root command if not set")
+	cmd.Flags().StringVar(&opts.Target, "target", "", "Executable the symlinks point to, this executable if not set")
+	cmd.Flags().StringArrayVar(&opts.Commands, "command", nil,
+		`Command to create a symlink for, e.g. "create cluster", all commands if not set`)
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the symlinks instead of creating them")
+	return cmd
+}
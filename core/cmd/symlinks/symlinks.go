@@ -0,0 +1,206 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package symlinks creates shortcuts for subcommands, symlinks named e.g. "dkp-create-cluster" for "dkp create
+// cluster". They are resolved by symlinkexechandler.HandleExec in the main function of the executable.
+package symlinks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Link is a symlink for a command.
+type Link struct {
+	// Name is the file name of the symlink, e.g. "dkp-create-cluster".
+	Name string
+	// Command is the path of the command below the root command, e.g. ["create", "cluster"].
+	Command []string
+}
+
+// Options configure the symlinks for a command tree.
+type Options struct {
+	// Prefix is the prefix passed to symlinkexechandler.HandleExec. Defaults to the name of the root command.
+	Prefix string
+	// Target is the executable the symlinks point to. Defaults to the running executable.
+	Target string
+	// Commands are the paths of the commands to link below the root command, e.g. "create cluster". If empty, all
+	// runnable commands that are neither hidden nor deprecated are linked.
+	Commands []string
+}
+
+// LinkName returns the name of the symlink for the command path, the inverse of the translation done by
+// symlinkexechandler.HandleExec: command names are joined with "-", after replacing "-" in names with "_".
+func LinkName(prefix string, command []string) string {
+	names := make([]string, 0, len(command)+1)
+	names = append(names, strings.TrimSuffix(prefix, "-"))
+	for _, name := range command {
+		names = append(names, strings.ReplaceAll(name, "-", "_"))
+	}
+	return strings.Join(names, "-")
+}
+
+// commandFromLinkName translates a symlink name back to the command path like symlinkexechandler.HandleExec.
+func commandFromLinkName(prefix, name string) []string {
+	command := strings.Split(strings.TrimPrefix(name, strings.TrimSuffix(prefix, "-")+"-"), "-")
+	for i := range command {
+		command[i] = strings.ReplaceAll(command[i], "_", "-")
+	}
+	return command
+}
+
+// Links returns the symlinks for the command tree, sorted by name. An error is returned for commands that cannot be
+// invoked through a symlink, because the encoding of their name is ambiguous (names containing "_") or collides with
+// the name of another command.
+func Links(root *cobra.Command, opts Options) ([]Link, error) {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = root.Name()
+	}
+
+	var commands [][]string
+	if len(opts.Commands) == 0 {
+		commands = runnableCommands(root, nil)
+	}
+	for _, path := range opts.Commands {
+		cmd, args, err := root.Find(strings.Fields(path))
+		if err != nil || len(args) > 0 || cmd == root {
+			return nil, fmt.Errorf("unknown command %q", path)
+		}
+		commands = append(commands, commandPath(root, cmd))
+	}
+
+	links := []Link{}
+	byName := map[string][]string{}
+	for _, command := range commands {
+		name := LinkName(prefix, command)
+		if other, ok := byName[name]; ok {
+			if strings.Join(other, " ") == strings.Join(command, " ") {
+				continue
+			}
+			return nil, fmt.Errorf("commands %q and %q have the same symlink name %s",
+				strings.Join(other, " "), strings.Join(command, " "), name)
+		}
+		if decoded := commandFromLinkName(prefix, name); strings.Join(decoded, " ") != strings.Join(command, " ") {
+			return nil, fmt.Errorf("command %q cannot be invoked through a symlink, %s would invoke %q",
+				strings.Join(command, " "), name, strings.Join(decoded, " "))
+		}
+		byName[name] = command
+		links = append(links, Link{Name: name, Command: command})
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+	return links, nil
+}
+
+func runnableCommands(cmd *cobra.Command, path []string) [][]string {
+	commands := [][]string{}
+	for _, subCmd := range cmd.Commands() {
+		if subCmd.Hidden || subCmd.Deprecated != "" || subCmd.Name() == "help" || subCmd.Name() == "completion" {
+			continue
+		}
+		subPath := append(path[:len(path):len(path)], subCmd.Name())
+		if subCmd.Runnable() {
+			commands = append(commands, subPath)
+		}
+		commands = append(commands, runnableCommands(subCmd, subPath)...)
+	}
+	return commands
+}
+
+// commandPath returns the names of cmd and its parents below root.
+func commandPath(root, cmd *cobra.Command) []string {
+	path := []string{}
+	for ; cmd != root && cmd != nil; cmd = cmd.Parent() {
+		path = append([]string{cmd.Name()}, path...)
+	}
+	return path
+}
+
+// Create creates the symlinks for the command tree in dir, replacing symlinks to other targets. Existing files that
+// aren't symlinks are not replaced. Symlinks that already point to the target are kept, so Create can be run
+// repeatedly. It returns the created symlinks.
+func Create(root *cobra.Command, dir string, opts Options) ([]Link, error) {
+	links, err := Links(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveTarget(opts.Target)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Directory of executables.
+		return nil, err
+	}
+
+	created := []Link{}
+	for _, link := range links {
+		path := filepath.Join(dir, link.Name)
+		existing, err := os.Readlink(path)
+		switch {
+		case err == nil && existing == target:
+			continue
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return created, fmt.Errorf("cannot replace %s: %w", path, err)
+		}
+		// create the symlink next to the existing one first, so it is replaced atomically
+		tmpPath := filepath.Join(dir, "."+link.Name+".tmp")
+		_ = os.Remove(tmpPath)
+		if err := os.Symlink(target, tmpPath); err != nil {
+			return created, err
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			_ = os.Remove(tmpPath)
+			return created, err
+		}
+		created = append(created, link)
+	}
+	return created, nil
+}
+
+// Remove removes the symlinks for the command tree from dir. Only symlinks pointing to the target are removed. It
+// returns the removed symlinks.
+func Remove(root *cobra.Command, dir string, opts Options) ([]Link, error) {
+	links, err := Links(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveTarget(opts.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []Link{}
+	for _, link := range links {
+		path := filepath.Join(dir, link.Name)
+		if existing, err := os.Readlink(path); err != nil || existing != target {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, link)
+	}
+	return removed, nil
+}
+
+// resolveTarget returns the absolute path of the target, the running executable if target is empty.
+func resolveTarget(target string) (string, error) {
+	if target == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+		target = executable
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(target)
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package symlinks_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/symlinks"
+)
+
+func run(cmd *cobra.Command, args []string) {}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{Use: "dkp"}
+	create := &cobra.Command{Use: "create"}
+	create.AddCommand(
+		&cobra.Command{Use: "cluster", Run: run},
+		&cobra.Command{Use: "node-pool", Run: run},
+		&cobra.Command{Use: "secret", Run: run, Hidden: true},
+	)
+	root.AddCommand(
+		create,
+		&cobra.Command{Use: "version", Run: run},
+		&cobra.Command{Use: "old", Run: run, Deprecated: "use version"},
+	)
+	return root
+}
+
+func linkNames(links []symlinks.Link) []string {
+	names := []string{}
+	for _, link := range links {
+		names = append(names, link.Name)
+	}
+	return names
+}
+
+func TestLinks(t *testing.T) {
+	root := newRootCommand()
+
+	links, err := symlinks.Links(root, symlinks.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []symlinks.Link{
+		{Name: "dkp-create-cluster", Command: []string{"create", "cluster"}},
+		{Name: "dkp-create-node_pool", Command: []string{"create", "node-pool"}},
+		{Name: "dkp-version", Command: []string{"version"}},
+	}, links)
+
+	links, err = symlinks.Links(root, symlinks.Options{
+		Prefix:   "kommander-",
+		Commands: []string{"create secret", "create"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kommander-create", "kommander-create-secret"}, linkNames(links))
+
+	_, err = symlinks.Links(root, symlinks.Options{Commands: []string{"delete cluster"}})
+	assert.EqualError(t, err, `unknown command "delete cluster"`)
+
+	root.AddCommand(&cobra.Command{Use: "snake_case", Run: run})
+	_, err = symlinks.Links(root, symlinks.Options{})
+	assert.EqualError(t, err,
+		`command "snake_case" cannot be invoked through a symlink, dkp-snake_case would invoke "snake-case"`)
+	_, err = symlinks.Links(root, symlinks.Options{Commands: []string{"version", "version"}})
+	assert.NoError(t, err)
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	root := newRootCommand()
+	dir := filepath.Join(t.TempDir(), "bin")
+	target := filepath.Join(t.TempDir(), "dkp")
+	otherTarget := filepath.Join(t.TempDir(), "dkp")
+	require.NoError(t, os.WriteFile(target, nil, 0o600))
+	require.NoError(t, os.WriteFile(otherTarget, nil, 0o600))
+	opts := symlinks.Options{Target: target}
+
+	created, err := symlinks.Create(root, dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dkp-create-cluster", "dkp-create-node_pool", "dkp-version"}, linkNames(created))
+	for _, name := range linkNames(created) {
+		linkTarget, err := os.Readlink(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, target, linkTarget)
+	}
+
+	// idempotent
+	created, err = symlinks.Create(root, dir, opts)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	// symlinks to other targets are replaced, other files are not
+	require.NoError(t, os.Remove(filepath.Join(dir, "dkp-version")))
+	require.NoError(t, os.Symlink(otherTarget, filepath.Join(dir, "dkp-version")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "dkp-create-cluster")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-create-cluster"), nil, 0o600))
+	created, err = symlinks.Create(root, dir, opts)
+	assert.ErrorContains(t, err, "cannot replace "+filepath.Join(dir, "dkp-create-cluster"))
+	assert.Empty(t, created)
+	require.NoError(t, os.Remove(filepath.Join(dir, "dkp-create-cluster")))
+	created, err = symlinks.Create(root, dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dkp-create-cluster", "dkp-version"}, linkNames(created))
+
+	// only symlinks to the target are removed
+	require.NoError(t, os.Remove(filepath.Join(dir, "dkp-version")))
+	require.NoError(t, os.Symlink(otherTarget, filepath.Join(dir, "dkp-version")))
+	removed, err := symlinks.Remove(root, dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dkp-create-cluster", "dkp-create-node_pool"}, linkNames(removed))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dkp-version", entries[0].Name())
+}
+
+func TestCommand(t *testing.T) {
+	root := newRootCommand()
+	out := &bytes.Buffer{}
+	root.AddCommand(symlinks.NewCommand(out, root))
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "dkp")
+	require.NoError(t, os.WriteFile(target, nil, 0o600))
+
+	root.SetArgs([]string{"_symlinks", dir, "--target", target, "--command", "create cluster"})
+	require.NoError(t, root.Execute())
+	assert.Equal(t, "Created dkp-create-cluster\n", out.String())
+	assert.FileExists(t, filepath.Join(dir, "dkp-create-cluster"))
+
+	out.Reset()
+	root.SetArgs([]string{"_symlinks", dir, "--target", target, "--remove"})
+	require.NoError(t, root.Execute())
+	assert.Equal(t, "Removed dkp-create-cluster\n", out.String())
+	assert.NoFileExists(t, filepath.Join(dir, "dkp-create-cluster"))
+}
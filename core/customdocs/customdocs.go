@@ -9,9 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Template interface is implemented by core packages text/template and html/template.
@@ -19,32 +19,96 @@ type Template interface {
 	Execute(wr io.Writer, data interface{}) error
 }
 
+// templateValues are the values templates are executed with.
 type templateValues struct {
-	Name        string
-	Short       string
-	Long        string
-	UseLine     string
-	Example     string
+	Name    string
+	Short   string
+	Long    string
+	UseLine string
+	Example string
+	// Link is the link to the documentation of this command.
+	Link string
+	// Flags and ParentFlags are the local and inherited flags formatted like in the usage of the command.
 	Flags       string
 	ParentFlags string
 	Links       []linkValue
+
+	// CommandName is the name of the command without the names of its parents.
+	CommandName string
+	Aliases     []string
+	// Deprecated is the deprecation notice, empty if the command isn't deprecated.
+	Deprecated  string
+	Annotations map[string]string
+	Runnable    bool
+	// GroupID is the ID of the group of the parent's commands this command belongs to.
+	GroupID string
+	// Groups are the groups of this command's sub-commands.
+	Groups []groupValue
+	// LocalFlags and InheritedFlags are the flags of the command, including hidden flags, sorted by name.
+	LocalFlags     []flagValue
+	InheritedFlags []flagValue
+
+	cmd    *cobra.Command
+	linker linker
 }
 
 type linkValue struct {
 	Name  string
 	Short string
+	// Link is the link to the documentation of the command.
+	Link string
+}
+
+// linker returns the link to the documentation of a command.
+type linker func(cmd *cobra.Command) string
+
+type groupValue struct {
+	ID    string
+	Title string
+	// Commands are the available sub-commands in this group.
+	Commands []linkValue
+}
+
+type flagValue struct {
+	Name       string
+	Shorthand  string
+	Type       string
+	Default    string
+	Usage      string
+	Deprecated string
+	Hidden     bool
+	// Annotations are the annotations of the flag, e.g. cobra.BashCompOneRequiredFlag for required flags.
+	Annotations map[string][]string
 }
 
-// GenWithTemplate outputs CLI docs based on the provided template (text/template or html/template).
+// GenWithTemplate outputs CLI docs based on the provided template (text/template or html/template). Templates can use
+// the functions of FuncMap if they are added before parsing.
 func GenWithTemplate(cmd *cobra.Command, w io.Writer, template Template) error {
 	cmd.InitDefaultHelpCmd()
+	return template.Execute(w, newTemplateValues(cmd, func(target *cobra.Command) string {
+		return defaultFilename(target, false)
+	}))
+}
+
+func newTemplateValues(cmd *cobra.Command, link linker) *templateValues {
 	cmd.InitDefaultHelpFlag()
 
-	values := templateValues{
-		Name:    cmd.CommandPath(),
-		Short:   cmd.Short,
-		Long:    cmd.Long,
-		Example: cmd.Example,
+	values := &templateValues{
+		Name:           cmd.CommandPath(),
+		Short:          cmd.Short,
+		Long:           cmd.Long,
+		Example:        cmd.Example,
+		Link:           link(cmd),
+		CommandName:    cmd.Name(),
+		Aliases:        cmd.Aliases,
+		Deprecated:     cmd.Deprecated,
+		Annotations:    cmd.Annotations,
+		Runnable:       cmd.Runnable(),
+		GroupID:        cmd.GroupID,
+		LocalFlags:     flagValues(cmd.NonInheritedFlags()),
+		InheritedFlags: flagValues(cmd.InheritedFlags()),
+		cmd:            cmd,
+		linker:         link,
 	}
 
 	if cmd.Runnable() {
@@ -74,27 +138,85 @@ func GenWithTemplate(cmd *cobra.Command, w io.Writer, template Template) error {
 			values.Links = append(values.Links, linkValue{
 				Name:  parent.CommandPath(),
 				Short: parent.Short,
+				Link:  link(parent),
 			})
 		}
 
-		children := cmd.Commands()
-		sort.Sort(byName(children))
-		for _, child := range children {
-			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
-				continue
-			}
+		for _, child := range availableChildren(cmd) {
 			values.Links = append(values.Links, linkValue{
 				Name:  child.CommandPath(),
 				Short: child.Short,
+				Link:  link(child),
 			})
 		}
 	}
 
-	return template.Execute(w, values)
+	for _, group := range cmd.Groups() {
+		groupValue := groupValue{ID: group.ID, Title: group.Title, Commands: []linkValue{}}
+		for _, child := range availableChildren(cmd) {
+			if child.GroupID == group.ID {
+				groupValue.Commands = append(groupValue.Commands, linkValue{
+					Name:  child.CommandPath(),
+					Short: child.Short,
+					Link:  link(child),
+				})
+			}
+		}
+		values.Groups = append(values.Groups, groupValue)
+	}
+
+	return values
+}
+
+// Parent returns the values of the parent command, nil for the root command.
+func (v *templateValues) Parent() *templateValues {
+	if !v.cmd.HasParent() {
+		return nil
+	}
+	return newTemplateValues(v.cmd.Parent(), v.linker)
+}
+
+// Children returns the values of the available sub-commands, sorted by name.
+func (v *templateValues) Children() []*templateValues {
+	children := []*templateValues{}
+	for _, child := range availableChildren(v.cmd) {
+		children = append(children, newTemplateValues(child, v.linker))
+	}
+	return children
+}
+
+func flagValues(flags *pflag.FlagSet) []flagValue {
+	values := []flagValue{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		values = append(values, flagValue{
+			Name:        flag.Name,
+			Shorthand:   flag.Shorthand,
+			Type:        flag.Value.Type(),
+			Default:     flag.DefValue,
+			Usage:       flag.Usage,
+			Deprecated:  flag.Deprecated,
+			Hidden:      flag.Hidden,
+			Annotations: flag.Annotations,
+		})
+	})
+	return values
+}
+
+// availableChildren returns the sub-commands of cmd that are documented, sorted by name.
+func availableChildren(cmd *cobra.Command) []*cobra.Command {
+	children := []*cobra.Command{}
+	for _, child := range cmd.Commands() {
+		if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		children = append(children, child)
+	}
+	sort.Sort(byName(children))
+	return children
 }
 
 // GenTreeWithTemplate outputs CLI docs for the command and all sub-commands into a directory,
-// using the provided template.
+// using the provided template. See GenTreeWithOptions to configure the files.
 func GenTreeWithTemplate(cmd *cobra.Command, dir string, template Template) error {
 	for _, c := range cmd.Commands() {
 		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
@@ -105,8 +227,7 @@ func GenTreeWithTemplate(cmd *cobra.Command, dir string, template Template) erro
 		}
 	}
 
-	basename := strings.ReplaceAll(cmd.CommandPath(), " ", "_")
-	filename := filepath.Join(dir, basename)
+	filename := filepath.Join(dir, defaultFilename(cmd, false))
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
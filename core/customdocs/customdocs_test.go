@@ -5,8 +5,10 @@ package customdocs_test
 
 import (
 	"bytes"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"text/template"
 
@@ -55,6 +57,55 @@ func TestGenTreeWithTemplate(t *testing.T) {
 	}
 }
 
+func TestGenWithTemplateStructuredValues(t *testing.T) {
+	rootCmd, cmd := setupTestCommands()
+	rootCmd.AddGroup(&cobra.Group{ID: "examples", Title: "Example Commands"})
+	cmd.GroupID = "examples"
+	cmd.Aliases = []string{"cmd"}
+	cmd.Annotations = map[string]string{"stability": "beta"}
+	cmd.Flags().StringP("name", "n", "", "the **name**")
+	require.NoError(t, cmd.MarkFlagRequired("name"))
+	cmd.Flags().Int("old", 0, "an old flag")
+	require.NoError(t, cmd.Flags().MarkDeprecated("old", "use --name"))
+
+	tpl, err := template.New("test").Funcs(customdocs.FuncMap()).Parse(`{{.CommandName}} {{.Aliases}} ` +
+		`{{.Annotations.stability}} {{.Runnable}} {{.GroupID}} {{.Parent.Name}} {{anchor .Name}}
+{{range .LocalFlags}}{{.Name}}|{{.Shorthand}}|{{.Type}}|{{.Default}}|{{markdown .Usage}}|{{.Deprecated}}|{{.Hidden}}|` +
+		`{{index .Annotations "cobra_annotation_bash_completion_one_required_flag"}}
+{{end}}{{range .InheritedFlags}}{{.Name}}
+{{end}}{{range .Children}}{{.Name}} {{.Runnable}} {{.Parent.Name}}
+{{end}}{{range .Parent.Groups}}{{.ID}} {{.Title}}:{{range .Commands}} {{.Name}}{{end}}
+{{end}}{{indent 2 .Long}}
+`)
+	require.NoError(t, err)
+
+	output := new(bytes.Buffer)
+	require.NoError(t, customdocs.GenWithTemplate(cmd, output, tpl))
+	assert.Equal(t, `command [cmd] beta true examples example example-command
+help|h|bool|false|<p>help for command</p>
+||false|[]
+local||string|default|<p>a local flag</p>
+||false|[]
+name|n|string||<p>the <strong>name</strong></p>
+||false|[true]
+old||int|0|<p>an old flag</p>
+|use --name|true|[]
+global
+example command sub true example command
+examples Example Commands: example command
+  This is just a sample for testing doc generation
+`, output.String())
+}
+
+func TestFuncMap(t *testing.T) {
+	tpl, err := template.New("test").Funcs(customdocs.FuncMap()).Parse(
+		`{{anchor "dkp create cluster"}} {{anchor "--Flag_Name!"}}|{{indent 4 "a\n\nb"}}`)
+	require.NoError(t, err)
+	output := new(bytes.Buffer)
+	require.NoError(t, tpl.Execute(output, nil))
+	assert.Equal(t, "dkp-create-cluster flag_name|    a\n\n    b", output.String())
+}
+
 func setupTestCommands() (*cobra.Command, *cobra.Command) {
 	rootCmd := &cobra.Command{
 		Use:   "example",
@@ -81,3 +132,86 @@ func setupTestCommands() (*cobra.Command, *cobra.Command) {
 
 	return rootCmd, cmd
 }
+
+func TestGenTreeWithOptions(t *testing.T) {
+	rootCmd, _ := setupTestCommands()
+	tpl, err := template.New("doc").Parse(`{{.Name}}:{{range .Links}} [{{.Name}}]({{.Link}}){{end}}`)
+	require.NoError(t, err)
+	index, err := template.New("index").Parse(`{{range .Commands}}- [{{.Name}}]({{.Link}})
+{{end}}`)
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "outdated"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outdated", "example_old.md"), nil, 0o600))
+
+	// hand-written files are not removed by accident
+	opts := customdocs.TreeOptions{
+		Extension: ".md",
+		Nested:    true,
+		FrontMatter: func(cmd *cobra.Command) string {
+			return "---\ntitle: " + cmd.Name() + "\n---\n"
+		},
+		Index: index,
+	}
+	err = customdocs.GenTreeWithOptions(rootCmd, dir, tpl, opts)
+	assert.ErrorContains(t, err, "is not empty and was not generated")
+	assert.FileExists(t, filepath.Join(dir, "outdated", "example_old.md"))
+
+	opts.Overwrite = true
+	require.NoError(t, customdocs.GenTreeWithOptions(rootCmd, dir, tpl, opts))
+	assert.Equal(t, map[string]string{
+		"index.md": "- [example](example/index.md)\n" +
+			"- [example command](example/command/index.md)\n" +
+			"- [example command sub](example/command/sub/index.md)\n",
+		"example/index.md": "---\ntitle: example\n---\n" +
+			"example: [example command](command/index.md)",
+		"example/command/index.md": "---\ntitle: command\n---\n" +
+			"example command: [example](../index.md) [example command sub](sub/index.md)",
+		"example/command/sub/index.md": "---\ntitle: sub\n---\n" +
+			"example command sub: [example command](../index.md)",
+	}, readTree(t, dir))
+	assert.FileExists(t, filepath.Join(dir, customdocs.MarkerFile))
+
+	err = customdocs.GenTreeWithOptions(rootCmd, dir, tpl, customdocs.TreeOptions{
+		Filename: func(cmd *cobra.Command) string {
+			return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+		},
+		Extension: ".html",
+		Link: func(filename string) string {
+			return "/cli/" + strings.TrimSuffix(filename, ".html") + "/"
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"example.html": "example: [example command](/cli/example-command/)",
+		"example-command.html": "example command: [example](/cli/example/) " +
+			"[example command sub](/cli/example-command-sub/)",
+		"example-command-sub.html": "example command sub: [example command](/cli/example-command/)",
+	}, readTree(t, dir))
+
+	err = customdocs.GenTreeWithOptions(rootCmd, dir, tpl, customdocs.TreeOptions{
+		Filename: func(cmd *cobra.Command) string { return "same" },
+	})
+	assert.EqualError(t, err, `commands "example" and "example command" have the same file same`)
+	assert.Len(t, readTree(t, dir), 3)
+}
+
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() == customdocs.MarkerFile {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
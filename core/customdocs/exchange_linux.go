@@ -0,0 +1,12 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package customdocs
+
+import "golang.org/x/sys/unix"
+
+// exchangeDirs atomically exchanges the directories a and b. It fails if either doesn't exist or the file system
+// doesn't support it.
+func exchangeDirs(a, b string) error {
+	return unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+}
@@ -0,0 +1,13 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package customdocs
+
+import "errors"
+
+// exchangeDirs is not supported on this platform, directories are replaced by renaming them one after the other.
+func exchangeDirs(a, b string) error {
+	return errors.New("exchanging directories is not supported")
+}
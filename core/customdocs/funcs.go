@@ -0,0 +1,59 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package customdocs
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"unicode"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// FuncMap returns functions for templates passed to GenWithTemplate and GenTreeWithTemplate. It can be added to
+// text/template and html/template templates before parsing:
+//
+//   - indent N TEXT indents all non-empty lines of TEXT by N spaces.
+//   - markdown TEXT renders Markdown to HTML, e.g. the long description of a command.
+//   - anchor TEXT returns a slug for use as an HTML anchor, e.g. "dkp-create-cluster" for "dkp create cluster".
+func FuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"indent":   indent,
+		"markdown": markdown,
+		"anchor":   anchor,
+	}
+}
+
+func indent(spaces int, text string) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func markdown(text string) htmltemplate.HTML {
+	//nolint:gosec // Documentation written by the authors of the commands.
+	return htmltemplate.HTML(blackfriday.Run([]byte(text)))
+}
+
+func anchor(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package customdocs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// MarkerFile is written into the output directory of GenTreeWithOptions. It marks the directory as generated, so it
+// can be replaced when the documentation is generated again.
+const MarkerFile = ".customdocs"
+
+// TreeOptions configure the files written by GenTreeWithOptions.
+type TreeOptions struct {
+	// Filename returns the path of the file for the command relative to the output directory, using "/" as separator
+	// and without extension. Defaults to the command path with spaces replaced by "_", or to "<command path>/index"
+	// with Nested.
+	Filename func(cmd *cobra.Command) string
+	// Extension is appended to the file names, e.g. ".md".
+	Extension string
+	// Nested puts the file of each command into a directory named after the command, which is nested in the
+	// directory of its parent, e.g. "example/command/index.md".
+	Nested bool
+	// Link returns the link to a file, given its path relative to the output directory (e.g. "example_command.md").
+	// Links in templates (e.g. .Links) are relative to the file they are used in by default.
+	Link func(filename string) string
+	// FrontMatter returns text written at the beginning of the file for the command, e.g. YAML front matter.
+	FrontMatter func(cmd *cobra.Command) string
+	// Index is executed with the values of all commands in .Commands to write an index or sidebar. Not written if nil.
+	Index Template
+	// IndexFilename is the path of the index relative to the output directory, without extension. Defaults to
+	// "index".
+	IndexFilename string
+	// Overwrite allows replacing an output directory that is not empty and was not generated before, i.e. it has no
+	// MarkerFile. Its files are removed.
+	Overwrite bool
+}
+
+type indexValues struct {
+	// Commands are the documented commands, each followed by its sub-commands.
+	Commands []*templateValues
+}
+
+// GenTreeWithOptions outputs CLI docs for the command and all sub-commands into a directory like
+// GenTreeWithTemplate, configured by opts.
+//
+// The files are written to a temporary directory first, which then replaces dir. So dir only contains the
+// generated files, and the documentation is never partially updated. To not remove files that were written by hand,
+// dir must be empty, missing or generated before, unless opts.Overwrite is set.
+func GenTreeWithOptions(cmd *cobra.Command, dir string, template Template, opts TreeOptions) error {
+	if dir == "" {
+		return errors.New("output directory must not be empty")
+	}
+	dir = filepath.Clean(dir)
+	if err := checkReplaceable(dir, opts.Overwrite); err != nil {
+		return err
+	}
+	cmd.InitDefaultHelpCmd()
+
+	commands := documentedCommands(cmd)
+	filenames := map[*cobra.Command]string{}
+	owners := map[string]*cobra.Command{}
+	for _, c := range commands {
+		filename := opts.filename(c)
+		if other, ok := owners[filename]; ok {
+			return fmt.Errorf("commands %q and %q have the same file %s", other.CommandPath(), c.CommandPath(), filename)
+		}
+		filenames[c] = filename
+		owners[filename] = c
+	}
+	indexFilename := opts.indexFilename()
+	if other, ok := owners[indexFilename]; ok && opts.Index != nil {
+		return fmt.Errorf("command %q has the same file as the index %s", other.CommandPath(), indexFilename)
+	}
+
+	// links from the file at from to the file of target
+	linkFrom := func(from string) linker {
+		return func(target *cobra.Command) string {
+			filename, ok := filenames[target]
+			if !ok {
+				filename = opts.filename(target)
+			}
+			if opts.Link != nil {
+				return opts.Link(filename)
+			}
+			link, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(filename))
+			if err != nil {
+				return filename
+			}
+			return filepath.ToSlash(link)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil { //nolint:gosec // Documentation is public.
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, c := range commands {
+		buf := new(bytes.Buffer)
+		if opts.FrontMatter != nil {
+			buf.WriteString(opts.FrontMatter(c))
+		}
+		if err := template.Execute(buf, newTemplateValues(c, linkFrom(filenames[c]))); err != nil {
+			return fmt.Errorf("failed to generate docs for %q: %w", c.CommandPath(), err)
+		}
+		if err := writeDocFile(tmpDir, filenames[c], buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if opts.Index != nil {
+		values := indexValues{Commands: make([]*templateValues, 0, len(commands))}
+		link := linkFrom(indexFilename)
+		for _, c := range commands {
+			values.Commands = append(values.Commands, newTemplateValues(c, link))
+		}
+		buf := new(bytes.Buffer)
+		if err := opts.Index.Execute(buf, values); err != nil {
+			return fmt.Errorf("failed to generate index: %w", err)
+		}
+		if err := writeDocFile(tmpDir, indexFilename, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	marker := []byte("Generated documentation, this directory is replaced when it is generated again.\n")
+	if err := writeDocFile(tmpDir, MarkerFile, marker); err != nil {
+		return err
+	}
+	return replaceDir(tmpDir, dir)
+}
+
+func (o TreeOptions) filename(cmd *cobra.Command) string {
+	if o.Filename != nil {
+		return path.Clean(o.Filename(cmd)) + o.Extension
+	}
+	return defaultFilename(cmd, o.Nested) + o.Extension
+}
+
+func (o TreeOptions) indexFilename() string {
+	if o.IndexFilename != "" {
+		return path.Clean(o.IndexFilename) + o.Extension
+	}
+	return "index" + o.Extension
+}
+
+// defaultFilename returns the path of the file for the command, without extension.
+func defaultFilename(cmd *cobra.Command, nested bool) string {
+	if nested {
+		return strings.ReplaceAll(cmd.CommandPath(), " ", "/") + "/index"
+	}
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "_")
+}
+
+// documentedCommands returns the command and all available sub-commands, each followed by its sub-commands.
+func documentedCommands(cmd *cobra.Command) []*cobra.Command {
+	commands := []*cobra.Command{cmd}
+	for _, child := range availableChildren(cmd) {
+		commands = append(commands, documentedCommands(child)...)
+	}
+	return commands
+}
+
+func writeDocFile(dir, filename string, data []byte) error {
+	filename = filepath.Join(dir, filepath.FromSlash(filename))
+	if !strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+		return fmt.Errorf("file %s is outside of the output directory", filename)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil { //nolint:gosec // Documentation is public.
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644) //nolint:gosec // Documentation is public.
+}
+
+// checkReplaceable returns an error if dir contains files and no MarkerFile, unless overwrite is set.
+func checkReplaceable(dir string, overwrite bool) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && (len(entries) == 0 || overwrite)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, MarkerFile)); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return fmt.Errorf("output directory %s is not empty and was not generated (no %s file), refusing to replace it",
+		dir, MarkerFile)
+}
+
+// replaceDir replaces dir with newDir, removing the files in dir. Where supported, both directories are exchanged
+// atomically, otherwise dir is missing for a moment between renaming it and renaming newDir.
+func replaceDir(newDir, dir string) error {
+	if err := os.Chmod(newDir, 0o755); err != nil { //nolint:gosec // Documentation is public.
+		return err
+	}
+	if err := exchangeDirs(newDir, dir); err == nil {
+		// newDir contains the previous documentation now
+		return os.RemoveAll(newDir)
+	}
+
+	oldDir := newDir + ".old"
+	if err := os.Rename(dir, oldDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(newDir, dir); err != nil {
+		// restore the previous documentation
+		_ = os.Rename(oldDir, dir)
+		return err
+	}
+	return os.RemoveAll(oldDir)
+}
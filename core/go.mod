@@ -9,9 +9,11 @@ require (
 	github.com/go-logr/logr v1.2.3
 	github.com/jwalton/gchalk v1.3.0
 	github.com/mattn/go-isatty v0.0.17
-	github.com/spf13/cobra v1.5.0
+	github.com/russross/blackfriday/v2 v2.1.0
+	github.com/spf13/cobra v1.6.1
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.8.0
+	golang.org/x/sys v0.6.0
 	k8s.io/klog/v2 v2.80.1
 	sigs.k8s.io/yaml v1.3.0
 )
@@ -22,8 +24,6 @@ require (
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
 	github.com/jwalton/go-supportscolor v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	golang.org/x/sys v0.6.0 // indirect
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,136 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dag validates and schedules named units of work with dependencies between them, e.g. the tasks of package
+// tasks and the checks of package checks.
+package dag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node is a unit of work with dependencies on other nodes.
+type Node struct {
+	// Name uniquely identifies the node.
+	Name string
+	// DependsOn lists names of nodes that have to finish before this node.
+	DependsOn []string
+}
+
+// Validate makes sure all dependencies exist and there are no cycles. kind is what the nodes are called in errors,
+// e.g. "task".
+func Validate(kind string, nodes []Node) error {
+	byName := make(map[string]Node, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("cyclic %s dependencies: %s", kind, strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range byName[name].DependsOn {
+			if _, ok := byName[dependency]; !ok {
+				return fmt.Errorf("%s %q depends on unknown %s %q", kind, name, kind, dependency)
+			}
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schedule tracks the runs started by Start.
+type Schedule struct {
+	done  []chan struct{}
+	index map[string]int
+}
+
+// Start calls run for each of the validated nodes in its own goroutine. run is called with the index of the node and
+// waits for the dependencies of the node with wait, which returns the index of the dependency once its run returned.
+func Start(nodes []Node, run func(i int, wait func(dependency string) int)) *Schedule {
+	s := &Schedule{
+		done:  make([]chan struct{}, len(nodes)),
+		index: make(map[string]int, len(nodes)),
+	}
+	for i, node := range nodes {
+		s.done[i] = make(chan struct{})
+		s.index[node.Name] = i
+	}
+	for i := range nodes {
+		go func(i int) {
+			defer close(s.done[i])
+			run(i, func(dependency string) int {
+				j := s.index[dependency]
+				s.Wait(j)
+				return j
+			})
+		}(i)
+	}
+	return s
+}
+
+// Wait waits until the run of the node with index i returned.
+func (s *Schedule) Wait(i int) {
+	<-s.done[i]
+}
+
+// WaitAll waits until all runs returned.
+func (s *Schedule) WaitAll() {
+	for i := range s.done {
+		s.Wait(i)
+	}
+}
+
+// Semaphore limits the number of runs at the same time. A nil Semaphore doesn't limit them.
+type Semaphore chan struct{}
+
+// NewSemaphore returns a Semaphore allowing n runs at the same time, nil if n is not positive.
+func NewSemaphore(n int) Semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(Semaphore, n)
+}
+
+// Acquire waits until a run is allowed, or returns the error of the context if it is done first. The returned function
+// releases the semaphore again.
+func (s Semaphore) Acquire(ctx context.Context) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	select {
+	case s <- struct{}{}:
+		return func() { <-s }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dag_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/internal/dag"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, dag.Validate("node", []dag.Node{{Name: "a", DependsOn: []string{"b"}}, {Name: "b"}}))
+	assert.EqualError(t, dag.Validate("node", []dag.Node{{Name: "a", DependsOn: []string{"c"}}}),
+		`node "a" depends on unknown node "c"`)
+	assert.EqualError(t, dag.Validate("node", []dag.Node{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+	}), "cyclic node dependencies: a -> b -> a")
+}
+
+func TestStart(t *testing.T) {
+	nodes := []dag.Node{
+		{Name: "c", DependsOn: []string{"a", "b"}},
+		{Name: "a"},
+		{Name: "b", DependsOn: []string{"a"}},
+	}
+	var lock sync.Mutex
+	order := []string{}
+	dag.Start(nodes, func(i int, wait func(dependency string) int) {
+		for _, dependency := range nodes[i].DependsOn {
+			wait(dependency)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, nodes[i].Name)
+	}).WaitAll()
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestSemaphore(t *testing.T) {
+	release, err := dag.Semaphore(nil).Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	semaphore := dag.NewSemaphore(1)
+	release, err = semaphore.Acquire(context.Background())
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = semaphore.Acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	release()
+	release, err = semaphore.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
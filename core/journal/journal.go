@@ -0,0 +1,351 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package journal records executed commands in a local file, so users can tell exactly what they ran, e.g. when
+// reporting an issue.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+)
+
+const (
+	fileName = "journal.jsonl"
+
+	// DefaultMaxSize is the default size of the journal file in bytes at which the oldest entries are removed.
+	DefaultMaxSize = 1024 * 1024
+
+	// ExcludeAnnotation excludes a command and its sub-commands from the journal if set to "true".
+	ExcludeAnnotation = "exclude-from-journal"
+)
+
+// Entry describes a single command execution.
+type Entry struct {
+	ID        int               `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args,omitempty"`
+	Flags     map[string]string `json:"flags,omitempty"`
+	Duration  time.Duration     `json:"duration"`
+	ExitCode  int               `json:"exitCode"`
+	Error     string            `json:"error,omitempty"`
+	Version   string            `json:"version,omitempty"`
+}
+
+// Succeeded returns true if the command finished without an error.
+func (e Entry) Succeeded() bool {
+	return e.ExitCode == 0
+}
+
+// NewEntry creates an entry describing the execution of a command. Sensitive flag values and sensitive "name=value"
+// arguments are redacted (see redact.Args), other arguments are recorded as they are: commands taking secrets as
+// arguments should be annotated with ExcludeAnnotation. The exit code is taken from err if it has an ExitCode method
+// (like *exec.ExitError and *plugin.ExitError), 1 otherwise.
+func NewEntry(cmd *cobra.Command, args []string, start time.Time, err error) Entry {
+	entry := Entry{
+		Timestamp: start,
+		Command:   cmd.CommandPath(),
+		Args:      redact.Args(args),
+		Flags:     redact.ChangedFlags(cmd.Flags()),
+		Duration:  time.Since(start),
+		Version:   version.GetVersion().GitVersion,
+	}
+	if len(entry.Flags) == 0 {
+		entry.Flags = nil
+	}
+	if err != nil {
+		entry.ExitCode = 1
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() != 0 {
+			entry.ExitCode = exitErr.ExitCode()
+		}
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// IsExcluded returns true if the command or one of its parents is annotated with ExcludeAnnotation.
+func IsExcluded(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[ExcludeAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// Journal appends entries to and reads entries from a journal file. Entries are stored as JSON lines. Processes
+// appending to the same journal file at the same time are serialized by a lock file next to it.
+type Journal struct {
+	path string
+	// MaxSize is the size of the journal file in bytes at which the oldest entries are removed, so the remaining
+	// entries take at most half of it. Defaults to DefaultMaxSize, a negative value keeps all entries.
+	MaxSize int64
+}
+
+// New returns a Journal stored in the given file.
+func New(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// DefaultPath returns the default journal file for an application in the user's state directory.
+func DefaultPath(appName string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName, fileName), nil
+}
+
+// StateDir returns the directory for user-specific state data, i.e. $XDG_STATE_HOME or ~/.local/state on Unix
+// systems and %LocalAppData% on Windows.
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" {
+		return os.UserCacheDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
+
+// Path returns the file the journal is stored in.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Append adds an entry to the journal. The entry's ID is set to the next free ID, the ID of the last entry plus 1.
+// The oldest entries are removed if the journal file exceeds MaxSize.
+func (j *Journal) Append(entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := j.appendEntry(entry); err != nil {
+		return err
+	}
+	return j.rotate()
+}
+
+func (j *Journal) appendEntry(entry Entry) error {
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	entry.ID = 1
+	if len(last) > 0 {
+		previous := Entry{}
+		if err := json.Unmarshal(last, &previous); err != nil {
+			return fmt.Errorf("invalid last journal entry in %s: %w", j.path, err)
+		}
+		entry.ID = previous.ID + 1
+	}
+	return json.NewEncoder(f).Encode(entry)
+}
+
+// lastLine returns the last non-empty line of f, reading it backwards, so the size of the file doesn't matter.
+func lastLine(f *os.File) ([]byte, error) {
+	const chunkSize = 4096
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte{}
+	for offset := info.Size(); offset > 0; {
+		n := int64(chunkSize)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		chunk := make([]byte, n, n+int64(len(data)))
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		data = append(chunk, data...)
+		trimmed := bytes.TrimRight(data, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(data, "\n"), nil
+}
+
+// rotate removes the oldest entries if the journal file exceeds MaxSize, so the remaining entries take at most half
+// of it. The last entry is always kept, so IDs keep increasing. The file is replaced atomically.
+func (j *Journal) rotate() error {
+	maxSize := j.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	info, err := os.Stat(j.path)
+	if err != nil || maxSize < 0 || info.Size() <= maxSize {
+		return err
+	}
+
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	keep, size := len(lines)-1, len(lines[len(lines)-1])
+	for keep > 0 && int64(size+len(lines[keep-1])) <= maxSize/2 {
+		size += len(lines[keep-1])
+		keep--
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), "."+filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bytes.Join(lines[keep:], nil))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// lock takes the lock of the journal file, waiting for other processes to release it. The returned function releases
+// the lock.
+func (j *Journal) lock() (func(), error) {
+	f, err := os.OpenFile(j.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock journal %s: %w", j.path, err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// Entries returns all entries in the journal, oldest first. A missing journal file results in no entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024) //nolint:gomnd // Allow long lines, e.g. because of many arguments.
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid journal entry in %s, line %d: %w", j.path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Entry returns the entry with the given ID.
+func (j *Journal) Entry(id int) (Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("journal entry %d not found", id)
+}
+
+// Clear removes all entries from the journal.
+func (j *Journal) Clear() error {
+	if _, err := os.Stat(j.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Remove(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Filter selects journal entries.
+type Filter struct {
+	// Command selects entries whose command path contains this string.
+	Command string
+	// Since selects entries recorded at or after this time.
+	Since time.Time
+	// FailedOnly selects only entries of failed commands.
+	FailedOnly bool
+	// Limit selects only the latest entries, if greater than 0.
+	Limit int
+}
+
+// Apply returns the entries matching the filter.
+func (f Filter) Apply(entries []Entry) []Entry {
+	result := []Entry{}
+	for _, entry := range entries {
+		if f.Command != "" && !strings.Contains(entry.Command, f.Command) {
+			continue
+		}
+		if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+			continue
+		}
+		if f.FailedOnly && entry.Succeeded() {
+			continue
+		}
+		result = append(result, entry)
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result
+}
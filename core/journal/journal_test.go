@@ -0,0 +1,158 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package journal_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+)
+
+func TestJournal(t *testing.T) {
+	j := journal.New(filepath.Join(t.TempDir(), "nested", "journal.jsonl"))
+
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	start := time.Now().Add(-time.Hour)
+	require.NoError(t, j.Append(journal.Entry{Timestamp: start, Command: "dkp create cluster"}))
+	require.NoError(t, j.Append(journal.Entry{Timestamp: time.Now(), Command: "dkp get pods", ExitCode: 1}))
+
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 1, entries[0].ID)
+	assert.Equal(t, "dkp create cluster", entries[0].Command)
+	assert.Equal(t, 2, entries[1].ID)
+
+	entry, err := j.Entry(2)
+	require.NoError(t, err)
+	assert.Equal(t, "dkp get pods", entry.Command)
+	_, err = j.Entry(3)
+	assert.EqualError(t, err, "journal entry 3 not found")
+
+	info, err := os.Stat(j.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.NoError(t, j.Clear())
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	require.NoError(t, j.Clear())
+}
+
+func TestJournalConcurrentAppends(t *testing.T) {
+	j := journal.New(filepath.Join(t.TempDir(), "journal.jsonl"))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, journal.New(j.Path()).Append(journal.Entry{Command: "dkp get pods"}))
+		}()
+	}
+	wg.Wait()
+
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 20)
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.ID)
+	}
+}
+
+func TestJournalRotation(t *testing.T) {
+	j := journal.New(filepath.Join(t.TempDir(), "journal.jsonl"))
+	j.MaxSize = 1000
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, j.Append(journal.Entry{Command: "dkp get pods"}))
+	}
+
+	info, err := os.Stat(j.Path())
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), j.MaxSize)
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Less(t, len(entries), 50)
+	assert.Equal(t, 50, entries[len(entries)-1].ID)
+
+	// the last entry is kept even if it exceeds the maximum size, so IDs keep increasing
+	j.MaxSize = 10
+	require.NoError(t, j.Append(journal.Entry{Command: "dkp get pods"}))
+	entries, err = j.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 51, entries[0].ID)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	entries := []journal.Entry{
+		{ID: 1, Timestamp: now.Add(-2 * time.Hour), Command: "dkp create cluster"},
+		{ID: 2, Timestamp: now.Add(-time.Hour), Command: "dkp create cluster", ExitCode: 1},
+		{ID: 3, Timestamp: now, Command: "dkp get pods"},
+	}
+
+	ids := func(entries []journal.Entry) []int {
+		result := []int{}
+		for _, entry := range entries {
+			result = append(result, entry.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, ids(journal.Filter{}.Apply(entries)))
+	assert.Equal(t, []int{1, 2}, ids(journal.Filter{Command: "create"}.Apply(entries)))
+	assert.Equal(t, []int{2}, ids(journal.Filter{FailedOnly: true}.Apply(entries)))
+	assert.Equal(t, []int{2, 3}, ids(journal.Filter{Since: now.Add(-90 * time.Minute)}.Apply(entries)))
+	assert.Equal(t, []int{3}, ids(journal.Filter{Limit: 1}.Apply(entries)))
+}
+
+func TestNewEntry(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "dkp"}
+	cmd := &cobra.Command{Use: "login", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.Flags().String("user", "", "")
+	cmd.Flags().String("password", "", "")
+	rootCmd.AddCommand(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--user=admin", "--password=hunter2"}))
+
+	start := time.Now()
+	entry := journal.NewEntry(cmd, []string{"arg", "token=abc"}, start, errors.New("login failed"))
+	assert.Equal(t, start, entry.Timestamp)
+	assert.Equal(t, "dkp login", entry.Command)
+	assert.Equal(t, []string{"arg", "token=" + redact.Placeholder}, entry.Args)
+	assert.Equal(t, map[string]string{"user": "admin", "password": redact.Placeholder}, entry.Flags)
+	assert.Equal(t, 1, entry.ExitCode)
+	assert.Equal(t, "login failed", entry.Error)
+	assert.NotEmpty(t, entry.Version)
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	require.Error(t, err)
+	entry = journal.NewEntry(cmd, nil, start, fmt.Errorf("login failed: %w", err))
+	assert.Equal(t, 3, entry.ExitCode)
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	path, err := journal.DefaultPath("dkp")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/state", "dkp", "journal.jsonl"), path)
+}
@@ -6,39 +6,218 @@ package output
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/jwalton/gchalk"
 )
 
+// StatusKind is a machine-readable identifier of the outcome of an operation.
+type StatusKind string
+
+const (
+	StatusKindSuccess   StatusKind = "success"
+	StatusKindFailure   StatusKind = "failure"
+	StatusKindSkipped   StatusKind = "skipped"
+	StatusKindWarning   StatusKind = "warning"
+	StatusKindCancelled StatusKind = "cancelled"
+	StatusKindTimedOut  StatusKind = "timed-out"
+	// StatusKindCustom is the kind of statuses created with NewStatus and of statuses not implementing
+	// StatusWithKind.
+	StatusKindCustom StatusKind = "custom"
+)
+
+// EndOperationStatus describes the outcome of an operation, see Output.EndOperationWithStatus.
 type EndOperationStatus interface {
+	// Fprintln writes the status character followed by the formatted message (and reason, if set) to w.
 	Fprintln(w io.Writer, format string, a ...any) (n int, err error)
 }
 
+// StatusWithKind is implemented by statuses with a machine-readable kind, like the statuses of this package. See
+// KindOf.
+type StatusWithKind interface {
+	EndOperationStatus
+	// Kind returns the machine-readable kind of the status.
+	Kind() StatusKind
+}
+
+// StatusWithReason is implemented by statuses with an optional reason, like the statuses of this package. See
+// ReasonOf and WithReason.
+type StatusWithReason interface {
+	EndOperationStatus
+	// Reason returns an optional message explaining the status, e.g. why an operation was skipped.
+	Reason() string
+	// WithReason returns a copy of the status with the given reason.
+	WithReason(reason string) EndOperationStatus
+}
+
+// KindOf returns the kind of the status, StatusKindCustom if it does not implement StatusWithKind.
+func KindOf(endStatus EndOperationStatus) StatusKind {
+	if s, ok := endStatus.(StatusWithKind); ok {
+		return s.Kind()
+	}
+	return StatusKindCustom
+}
+
+// ReasonOf returns the reason of the status, empty if it does not implement StatusWithReason.
+func ReasonOf(endStatus EndOperationStatus) string {
+	if s, ok := endStatus.(StatusWithReason); ok {
+		return s.Reason()
+	}
+	return ""
+}
+
+// WithReason returns a copy of the status with the given reason, e.g.
+//
+//	out.EndOperationWithStatus(output.WithReason(output.Skipped(), "already installed"))
+//
+// Statuses not implementing StatusWithReason are wrapped, appending the reason to their message.
+func WithReason(endStatus EndOperationStatus, reason string) EndOperationStatus {
+	if s, ok := endStatus.(StatusWithReason); ok {
+		return s.WithReason(reason)
+	}
+	return statusWithReason{status: endStatus, reason: reason}
+}
+
+// statusWithReason adds a reason to a status not implementing StatusWithReason.
+type statusWithReason struct {
+	status EndOperationStatus
+	reason string
+}
+
+func (s statusWithReason) Fprintln(w io.Writer, format string, a ...any) (n int, err error) {
+	return s.status.Fprintln(w, "%s (%s)", fmt.Sprintf(format, a...), s.reason)
+}
+
+func (s statusWithReason) Kind() StatusKind {
+	return KindOf(s.status)
+}
+
+func (s statusWithReason) Reason() string {
+	return s.reason
+}
+
+func (s statusWithReason) WithReason(reason string) EndOperationStatus {
+	s.reason = reason
+	return s
+}
+
 type status struct {
+	kind            StatusKind
 	statusCharacter string
 	color           *gchalk.Builder
+	// style is the name of the color of built-in statuses, so outputs can apply it with their own color level.
+	style  string
+	reason string
 }
 
 func (s status) Fprintln(w io.Writer, format string, a ...any) (n int, err error) {
-	format = fmt.Sprintf(" %s %s\n", s.color.Sprintf(s.statusCharacter), format)
-	return fmt.Fprintf(w, format, a...)
+	return fmt.Fprintf(w, " %s %s\n", s.color.Sprintf(s.statusCharacter), s.message(format, a...))
+}
+
+// fprintlnWithChalk is like Fprintln, but colors built-in statuses with chalk.
+func (s status) fprintlnWithChalk(w io.Writer, chalk *gchalk.Builder, format string, a ...any) (n int, err error) {
+	if s.style != "" {
+		s.color = chalk.WithStyleMust(s.style)
+	}
+	return s.Fprintln(w, format, a...)
 }
 
+func (s status) Kind() StatusKind {
+	return s.kind
+}
+
+func (s status) Reason() string {
+	return s.reason
+}
+
+func (s status) WithReason(reason string) EndOperationStatus {
+	s.reason = reason
+	return s
+}
+
+// message formats the status message without status character and color.
+func (s status) message(format string, a ...any) string {
+	msg := fmt.Sprintf(format, a...)
+	if s.reason != "" {
+		msg += " (" + s.reason + ")"
+	}
+	return msg
+}
+
+// NewStatus creates a status of kind StatusKindCustom.
 func NewStatus(statusCharacter string, color *gchalk.Builder) EndOperationStatus {
+	return NewStatusOfKind(StatusKindCustom, statusCharacter, color)
+}
+
+// NewStatusOfKind creates a status of the given kind.
+func NewStatusOfKind(kind StatusKind, statusCharacter string, color *gchalk.Builder) EndOperationStatus {
 	return status{
+		kind:            kind,
 		statusCharacter: statusCharacter,
 		color:           color,
 	}
 }
 
+// newBuiltinStatus creates a status of the given kind, colored with the named style.
+func newBuiltinStatus(kind StatusKind, statusCharacter, style string) EndOperationStatus {
+	return status{
+		kind:            kind,
+		statusCharacter: statusCharacter,
+		color:           gchalk.Stderr.WithStyleMust(style),
+		style:           style,
+	}
+}
+
+// Success is the status of an operation that succeeded, a green "✓".
 func Success() EndOperationStatus {
-	return NewStatus("✓", gchalk.Stderr.WithGreen())
+	return newBuiltinStatus(StatusKindSuccess, "✓", "green")
 }
 
+// Failure is the status of an operation that failed, a red "✗".
 func Failure() EndOperationStatus {
-	return NewStatus("✗", gchalk.Stderr.WithRed())
+	return newBuiltinStatus(StatusKindFailure, "✗", "red")
 }
 
+// Skipped is the status of an operation that was skipped, a yellow "∅".
 func Skipped() EndOperationStatus {
-	return NewStatus("∅", gchalk.Stderr.WithYellow())
+	return newBuiltinStatus(StatusKindSkipped, "∅", "yellow")
+}
+
+// Warning is the status of an operation that finished with a warning, e.g. a check that failed with warning
+// severity, a yellow "!".
+func Warning() EndOperationStatus {
+	return newBuiltinStatus(StatusKindWarning, "!", "yellow")
+}
+
+// Cancelled is the status of an operation that was cancelled before it finished, a yellow "⊘".
+func Cancelled() EndOperationStatus {
+	return newBuiltinStatus(StatusKindCancelled, "⊘", "yellow")
+}
+
+// TimedOut is the status of an operation that did not finish in time, a red "⧗".
+func TimedOut() EndOperationStatus {
+	return newBuiltinStatus(StatusKindTimedOut, "⧗", "red")
+}
+
+// statusLevel returns the level used to log an operation's end status in non-interactive shells.
+func statusLevel(kind StatusKind) string {
+	switch kind {
+	case StatusKindFailure, StatusKindTimedOut:
+		return "ERR"
+	case StatusKindWarning, StatusKindCancelled:
+		return "WRN"
+	default:
+		return "INF"
+	}
+}
+
+// plainStatusLine formats an end status without colors, e.g. " ✓ installing package".
+func plainStatusLine(endStatus EndOperationStatus, msg string) string {
+	if s, ok := endStatus.(status); ok {
+		return fmt.Sprintf(" %s %s", s.statusCharacter, s.message("%s", msg))
+	}
+	// unknown implementation: let it render itself, without the trailing newline
+	line := &strings.Builder{}
+	_, _ = endStatus.Fprintln(line, "%s", msg)
+	return strings.TrimRight(line.String(), "\n")
 }
@@ -0,0 +1,136 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package output_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jwalton/gchalk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+func TestEndOperationStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		status output.EndOperationStatus
+		kind   output.StatusKind
+	}{
+		{output.Success(), output.StatusKindSuccess},
+		{output.Failure(), output.StatusKindFailure},
+		{output.Skipped(), output.StatusKindSkipped},
+		{output.Warning(), output.StatusKindWarning},
+		{output.Cancelled(), output.StatusKindCancelled},
+		{output.TimedOut(), output.StatusKindTimedOut},
+		{output.NewStatus("?", gchalk.Stderr.WithBlue()), output.StatusKindCustom},
+	} {
+		assert.Equal(test.kind, output.KindOf(test.status))
+		assert.Empty(output.ReasonOf(test.status))
+
+		withReason := output.WithReason(test.status, "a reason")
+		assert.Equal(test.kind, output.KindOf(withReason))
+		assert.Equal("a reason", output.ReasonOf(withReason))
+		assert.Empty(output.ReasonOf(test.status), "WithReason must not modify the original status")
+	}
+}
+
+// plainStatus implements EndOperationStatus only.
+type plainStatus struct{}
+
+func (plainStatus) Fprintln(w io.Writer, format string, a ...any) (n int, err error) {
+	return fmt.Fprintf(w, " * "+format+"\n", a...)
+}
+
+func TestEndOperationStatusWithoutKindAndReason(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(output.StatusKindCustom, output.KindOf(plainStatus{}))
+	assert.Empty(output.ReasonOf(plainStatus{}))
+
+	withReason := output.WithReason(plainStatus{}, "a reason")
+	assert.Equal(output.StatusKindCustom, output.KindOf(withReason))
+	assert.Equal("a reason", output.ReasonOf(withReason))
+
+	errOut := bytes.Buffer{}
+	o := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+	o.StartOperation("working")
+	o.EndOperationWithStatus(withReason)
+	assert.Contains(errOut.String(), "INF  * working (a reason)\n")
+}
+
+func TestEndOperationStatusNonInteractive(t *testing.T) {
+	assert := assert.New(t)
+
+	errOut := bytes.Buffer{}
+	o := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+
+	for _, status := range []output.EndOperationStatus{
+		output.Success(),
+		output.Failure(),
+		output.WithReason(output.Skipped(), "not applicable"),
+		output.Warning(),
+		output.Cancelled(),
+		output.WithReason(output.TimedOut(), "after 5m00s"),
+		output.NewStatus("?", gchalk.Stderr.WithBlue()),
+	} {
+		o.StartOperation("working on 100%")
+		o.EndOperationWithStatus(status)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(errOut.String(), "\n"), "\n") {
+		// strip timestamp
+		parts := strings.SplitN(line, " ", 3)
+		assert.Len(parts, 3)
+		lines = append(lines, parts[2])
+	}
+	assert.Equal([]string{
+		"INF  • working on 100%...",
+		"INF  ✓ working on 100%",
+		"INF  • working on 100%...",
+		"ERR  ✗ working on 100%",
+		"INF  • working on 100%...",
+		"INF  ∅ working on 100% (not applicable)",
+		"INF  • working on 100%...",
+		"WRN  ! working on 100%",
+		"INF  • working on 100%...",
+		"WRN  ⊘ working on 100%",
+		"INF  • working on 100%...",
+		"ERR  ⧗ working on 100% (after 5m00s)",
+		"INF  • working on 100%...",
+		"INF  ? working on 100%",
+	}, lines)
+}
+
+func TestEndOperationStatusInteractive(t *testing.T) {
+	origGchalkStderr := gchalk.Stderr
+	defer func() {
+		gchalk.Stderr = origGchalkStderr
+	}()
+	gchalk.Stderr = gchalk.New(
+		gchalk.ForceLevel(gchalk.LevelAnsi256),
+	)
+
+	errOut := bytes.Buffer{}
+	o := output.NewInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+	o.StartOperation("working")
+	o.EndOperationWithStatus(output.WithReason(output.Cancelled(), "interrupted"))
+	o.StartOperation("working")
+	o.EndOperationWithStatus(output.Warning())
+
+	lines := strings.Split(strings.TrimSuffix(errOut.String(), "\n"), "\n")
+	finalLine := func(line string) string {
+		subLines := strings.Split(line, "\r")
+		return subLines[len(subLines)-1]
+	}
+	assert.Len(t, lines, 2)
+	assert.Equal(t, " "+termYellow+"⊘"+termDefaultFg+" working (interrupted)", finalLine(lines[0]))
+	assert.Equal(t, " "+termYellow+"!"+termDefaultFg+" working", finalLine(lines[1]))
+}
@@ -0,0 +1,382 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jwalton/gchalk"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	EventTypeInfo           EventType = "info"
+	EventTypeWarn           EventType = "warn"
+	EventTypeError          EventType = "error"
+	EventTypeStartOperation EventType = "start-operation"
+	EventTypeProgress       EventType = "progress"
+	EventTypeEndOperation   EventType = "end-operation"
+	EventTypeResult         EventType = "result"
+)
+
+// Event is a single call of an Output method, serialized as one line of JSON by NewEventStreamOutput.
+type Event struct {
+	Type EventType `json:"type"`
+	// Level is the verbosity level of the Output the event was created with (see Output.V).
+	Level int `json:"level,omitempty"`
+	// KeysAndValues are added with Output.WithValues. Values are converted to strings.
+	KeysAndValues []string `json:"keys_and_values,omitempty"`
+	Message       string   `json:"message,omitempty"`
+	// Error is the message of the error passed to Output.Error.
+	Error string `json:"error,omitempty"`
+	// Progress is set for operations started with StartOperationWithProgress and for progress events.
+	Progress *EventProgress `json:"progress,omitempty"`
+	// Status is set for end-operation events.
+	Status *EventStatus `json:"status,omitempty"`
+	// Data is the output of result events.
+	Data string `json:"data,omitempty"`
+}
+
+// EventProgress describes the state of a ProgressGauge.
+type EventProgress struct {
+	Status   string `json:"status,omitempty"`
+	Current  int    `json:"current"`
+	Capacity int    `json:"capacity"`
+}
+
+// EventStatus describes an EndOperationStatus.
+type EventStatus struct {
+	Kind   StatusKind `json:"kind"`
+	Reason string     `json:"reason,omitempty"`
+	// Character is the status character of custom statuses.
+	Character string `json:"character,omitempty"`
+}
+
+// eventStreamProgressInterval is the interval gauges are checked for changes.
+const eventStreamProgressInterval = 100 * time.Millisecond
+
+// NewEventStreamOutput returns an Output writing all calls as JSON events to w, one per line. Use ReplayEvents to
+// render the events with another Output, e.g. in a host CLI running a plugin. That way, output of plugins looks
+// identical to output of built-in commands, even if the plugin is not connected to a terminal.
+func NewEventStreamOutput(w io.Writer, verbosity int) Output {
+	return &eventStreamOutput{
+		stream:    &eventStream{encoder: json.NewEncoder(w)},
+		verbosity: verbosity,
+	}
+}
+
+// eventStream is shared by an eventStreamOutput and all Outputs derived from it.
+type eventStream struct {
+	encoder *json.Encoder
+	lock    sync.Mutex
+
+	// gauge of the running operation and the channel to stop watching it
+	gauge     *ProgressGauge
+	stopGauge chan struct{}
+	gaugeDone chan struct{}
+}
+
+func (s *eventStream) emit(event Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// nothing we can do if the host is gone
+	_ = s.encoder.Encode(event)
+}
+
+// watchGauge emits progress events whenever the gauge changes, until the operation ends.
+func (s *eventStream) watchGauge(gauge *ProgressGauge, level int) {
+	s.lock.Lock()
+	s.gauge = gauge
+	s.stopGauge = make(chan struct{})
+	s.gaugeDone = make(chan struct{})
+	stop, done := s.stopGauge, s.gaugeDone
+	s.lock.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(eventStreamProgressInterval)
+		defer ticker.Stop()
+		last := progressOf(gauge)
+		for {
+			select {
+			case <-stop:
+				if current := progressOf(gauge); *current != *last {
+					s.emit(Event{Type: EventTypeProgress, Level: level, Progress: current})
+				}
+				return
+			case <-ticker.C:
+				if current := progressOf(gauge); *current != *last {
+					s.emit(Event{Type: EventTypeProgress, Level: level, Progress: current})
+					last = current
+				}
+			}
+		}
+	}()
+}
+
+// stopWatchingGauge stops watching the gauge of the running operation, emitting its final state.
+func (s *eventStream) stopWatchingGauge() {
+	s.lock.Lock()
+	stop, done := s.stopGauge, s.gaugeDone
+	s.gauge, s.stopGauge, s.gaugeDone = nil, nil, nil
+	s.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func progressOf(gauge *ProgressGauge) *EventProgress {
+	gauge.lock.RLock()
+	defer gauge.lock.RUnlock()
+	return &EventProgress{Status: gauge.status, Current: gauge.current, Capacity: gauge.capacity}
+}
+
+type eventStreamOutput struct {
+	stream *eventStream
+	// verbosity is the maximum V level that is written to the stream
+	verbosity int
+	// level is the V level of this instance
+	level         int
+	keysAndValues []string
+}
+
+func (o *eventStreamOutput) event(eventType EventType) Event {
+	return Event{Type: eventType, Level: o.level, KeysAndValues: o.keysAndValues}
+}
+
+func (o *eventStreamOutput) Info(msg string) {
+	event := o.event(EventTypeInfo)
+	event.Message = msg
+	o.stream.emit(event)
+}
+
+func (o *eventStreamOutput) Infof(format string, args ...interface{}) {
+	o.Info(fmt.Sprintf(format, args...))
+}
+
+func (o *eventStreamOutput) InfoWriter() io.Writer {
+	return msgWriter(o.Info)
+}
+
+func (o *eventStreamOutput) Warn(msg string) {
+	event := o.event(EventTypeWarn)
+	event.Message = msg
+	o.stream.emit(event)
+}
+
+func (o *eventStreamOutput) Warnf(format string, args ...interface{}) {
+	o.Warn(fmt.Sprintf(format, args...))
+}
+
+func (o *eventStreamOutput) WarnWriter() io.Writer {
+	return msgWriter(o.Warn)
+}
+
+func (o *eventStreamOutput) Error(err error, msg string) {
+	event := o.event(EventTypeError)
+	event.Message = msg
+	if err != nil {
+		event.Error = err.Error()
+	}
+	o.stream.emit(event)
+}
+
+func (o *eventStreamOutput) Errorf(err error, format string, args ...interface{}) {
+	o.Error(err, fmt.Sprintf(format, args...))
+}
+
+func (o *eventStreamOutput) ErrorWriter() io.Writer {
+	return msgWriter(func(msg string) {
+		o.Error(nil, msg)
+	})
+}
+
+func (o *eventStreamOutput) StartOperation(status string) {
+	o.stream.stopWatchingGauge()
+	event := o.event(EventTypeStartOperation)
+	event.Message = status
+	o.stream.emit(event)
+}
+
+func (o *eventStreamOutput) StartOperationWithProgress(gauge *ProgressGauge) {
+	o.stream.stopWatchingGauge()
+	event := o.event(EventTypeStartOperation)
+	event.Progress = progressOf(gauge)
+	o.stream.emit(event)
+	o.stream.watchGauge(gauge, o.level)
+}
+
+func (o *eventStreamOutput) EndOperation(success bool) {
+	if success {
+		o.EndOperationWithStatus(Success())
+	} else {
+		o.EndOperationWithStatus(Failure())
+	}
+}
+
+func (o *eventStreamOutput) EndOperationWithStatus(endStatus EndOperationStatus) {
+	o.stream.stopWatchingGauge()
+	event := o.event(EventTypeEndOperation)
+	event.Status = &EventStatus{Kind: KindOf(endStatus), Reason: ReasonOf(endStatus)}
+	if s, ok := endStatus.(status); ok && s.kind == StatusKindCustom {
+		event.Status.Character = s.statusCharacter
+	}
+	o.stream.emit(event)
+}
+
+func (o *eventStreamOutput) Result(result string) {
+	fmt.Fprintln(o.ResultWriter(), result)
+}
+
+func (o *eventStreamOutput) ResultWriter() io.Writer {
+	return eventResultWriter{stream: o.stream}
+}
+
+func (o *eventStreamOutput) Enabled(level int) bool {
+	return level <= o.verbosity
+}
+
+func (o *eventStreamOutput) V(level int) Output {
+	if !o.Enabled(level) {
+		return &noopOutput{Output: o}
+	}
+	return &eventStreamOutput{
+		stream:        o.stream,
+		verbosity:     o.verbosity,
+		level:         level,
+		keysAndValues: o.keysAndValues,
+	}
+}
+
+func (o *eventStreamOutput) WithValues(keysAndValues ...interface{}) Output {
+	values := append([]string{}, o.keysAndValues...)
+	for _, value := range keysAndValues {
+		values = append(values, fmt.Sprint(value))
+	}
+	return &eventStreamOutput{
+		stream:        o.stream,
+		verbosity:     o.verbosity,
+		level:         o.level,
+		keysAndValues: values,
+	}
+}
+
+// eventResultWriter emits everything written as result event.
+type eventResultWriter struct {
+	stream *eventStream
+}
+
+func (w eventResultWriter) Write(p []byte) (n int, err error) {
+	w.stream.emit(Event{Type: EventTypeResult, Data: string(p)})
+	return len(p), nil
+}
+
+// ReplayEvents reads events written by an Output created with NewEventStreamOutput from r and replays them with out,
+// until r is closed. Lines that are not valid events are output as info messages.
+func ReplayEvents(r io.Reader, out Output) error {
+	var gauge *ProgressGauge
+	scanner := bufio.NewScanner(r)
+	// results may be large
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 64*1024*1024) //nolint:gomnd // 64 MiB.
+
+	for scanner.Scan() {
+		event := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type == "" {
+			out.Info(scanner.Text())
+			continue
+		}
+
+		o := out
+		if event.Level > 0 {
+			o = o.V(event.Level)
+		}
+		if len(event.KeysAndValues) > 0 {
+			keysAndValues := make([]interface{}, 0, len(event.KeysAndValues))
+			for _, value := range event.KeysAndValues {
+				keysAndValues = append(keysAndValues, value)
+			}
+			o = o.WithValues(keysAndValues...)
+		}
+
+		switch event.Type {
+		case EventTypeInfo:
+			o.Info(event.Message)
+		case EventTypeWarn:
+			o.Warn(event.Message)
+		case EventTypeError:
+			var err error
+			if event.Error != "" {
+				err = errors.New(event.Error)
+			}
+			o.Error(err, event.Message)
+		case EventTypeStartOperation:
+			if event.Progress == nil {
+				gauge = nil
+				o.StartOperation(event.Message)
+				continue
+			}
+			gauge = &ProgressGauge{}
+			applyProgress(gauge, event.Progress)
+			o.StartOperationWithProgress(gauge)
+		case EventTypeProgress:
+			if gauge != nil && event.Progress != nil {
+				applyProgress(gauge, event.Progress)
+			}
+		case EventTypeEndOperation:
+			gauge = nil
+			o.EndOperationWithStatus(statusOf(event.Status))
+		case EventTypeResult:
+			_, _ = io.WriteString(out.ResultWriter(), event.Data)
+		default:
+			// events of newer versions are ignored
+		}
+	}
+	return scanner.Err()
+}
+
+func applyProgress(gauge *ProgressGauge, progress *EventProgress) {
+	gauge.SetStatus(progress.Status)
+	gauge.SetCapacity(progress.Capacity)
+	gauge.Set(progress.Current)
+	if progress.Capacity > 0 {
+		gauge.InitStartTime()
+	}
+}
+
+func statusOf(eventStatus *EventStatus) EndOperationStatus {
+	if eventStatus == nil {
+		return Success()
+	}
+	var endStatus EndOperationStatus
+	switch eventStatus.Kind {
+	case StatusKindSuccess:
+		endStatus = Success()
+	case StatusKindFailure:
+		endStatus = Failure()
+	case StatusKindSkipped:
+		endStatus = Skipped()
+	case StatusKindWarning:
+		endStatus = Warning()
+	case StatusKindCancelled:
+		endStatus = Cancelled()
+	case StatusKindTimedOut:
+		endStatus = TimedOut()
+	default:
+		endStatus = NewStatus(eventStatus.Character, gchalk.Stderr.WithReset())
+	}
+	if eventStatus.Reason != "" {
+		endStatus = WithReason(endStatus, eventStatus.Reason)
+	}
+	return endStatus
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package output_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jwalton/gchalk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+func writeTestOutput(o output.Output) {
+	o.Info("info")
+	o.V(1).Infof("verbose %d", 1)
+	o.V(2).Info("too verbose")
+	o.WithValues("key", "value", "number", 42).Warn("warning")
+	o.Error(errors.New("error"), "failed")
+	o.Error(nil, "failed without error")
+
+	o.StartOperation("working")
+	o.EndOperationWithStatus(output.WithReason(output.Skipped(), "not needed"))
+
+	gauge := &output.ProgressGauge{}
+	gauge.SetStatus("installing")
+	gauge.SetCapacity(2)
+	o.StartOperationWithProgress(gauge)
+	gauge.Inc()
+	time.Sleep(150 * time.Millisecond)
+	gauge.Inc()
+	o.EndOperationWithStatus(output.Success())
+
+	o.StartOperation("custom")
+	o.EndOperationWithStatus(output.NewStatus("?", gchalk.Stderr.WithBlue()))
+
+	o.Result("result")
+}
+
+// stripTimestamps removes the timestamps of non-interactive output.
+func stripTimestamps(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		parts := strings.SplitN(line, " ", 3)
+		lines = append(lines, parts[len(parts)-1])
+	}
+	return lines
+}
+
+func TestEventStreamOutput(t *testing.T) {
+	expectedOut, expectedErrOut := bytes.Buffer{}, bytes.Buffer{}
+	writeTestOutput(output.NewNonInteractiveShell(&expectedOut, &expectedErrOut, 1))
+
+	events := bytes.Buffer{}
+	writeTestOutput(output.NewEventStreamOutput(&events, 1))
+	assert.NotContains(t, events.String(), "too verbose")
+
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	require.NoError(t, output.ReplayEvents(&events, output.NewNonInteractiveShell(&out, &errOut, 1)))
+
+	assert.Equal(t, expectedOut.String(), out.String())
+	assert.Equal(t, stripTimestamps(expectedErrOut.String()), stripTimestamps(errOut.String()))
+}
+
+func TestReplayEventsInvalidLines(t *testing.T) {
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	require.NoError(t, output.ReplayEvents(
+		strings.NewReader("not an event\n{\"type\": \"future-event\"}\n{\"type\": \"info\", \"message\": \"info\"}\n"),
+		output.NewNonInteractiveShell(&out, &errOut, 0),
+	))
+	assert.Equal(t, []string{"INF not an event", "INF info"}, stripTimestamps(errOut.String()))
+}
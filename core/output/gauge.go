@@ -36,6 +36,11 @@ func (g *ProgressGauge) IsReady() bool {
 	}
 	g.lock.RLock()
 	defer g.lock.RUnlock()
+	return g.isReady()
+}
+
+// isReady must be called with the lock held.
+func (g *ProgressGauge) isReady() bool {
 	if g.current < 0 {
 		return false
 	}
@@ -118,12 +123,13 @@ func (g *ProgressGauge) String() string {
 		return ""
 	}
 
-	g.lock.RLock()
-	defer g.lock.RUnlock()
+	// not a read lock, the start time may be initialized
+	g.lock.Lock()
+	defer g.lock.Unlock()
 	if g.startTime.IsZero() {
 		g.startTime = time.Now()
 	}
-	if !g.IsReady() {
+	if !g.isReady() {
 		return fmt.Sprintf(" %s", g.status)
 	}
 	duration := HumanReadableDuration(time.Since(g.startTime))
@@ -48,6 +48,24 @@ func TestProgressGauge(t *testing.T) {
 	assert.Equal(t, " static-status", gauge.String())
 }
 
+func TestProgressGaugeConcurrentUpdates(t *testing.T) {
+	gauge := &output.ProgressGauge{}
+	gauge.SetCapacity(1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			gauge.SetStatus("status")
+			gauge.Inc()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		_ = gauge.String()
+	}
+	<-done
+	assert.True(t, gauge.IsReady())
+}
+
 func Test_humanReadableDuration(t *testing.T) {
 	assert.Equal(t, "00s", output.HumanReadableDuration(10*time.Millisecond))
 	assert.Equal(t, "01s", output.HumanReadableDuration(1000*time.Millisecond))
@@ -16,14 +16,25 @@ func NewInteractiveShell(out, errOut io.Writer, verbosity int) Output {
 	return &interactiveShellOutput{
 		out:       out,
 		errOut:    newSpinner(errOut),
+		chalk:     gchalk.Stderr,
 		verbosity: verbosity,
 		level:     0,
 	}
 }
 
+// NewInteractiveShellWithColorLevel is like NewInteractiveShell, but colors output with the given color level instead
+// of the level detected for stderr. Other outputs are not affected.
+func NewInteractiveShellWithColorLevel(out, errOut io.Writer, verbosity int, colorLevel gchalk.ColorLevel) Output {
+	o := NewInteractiveShell(out, errOut, verbosity).(*interactiveShellOutput)
+	o.chalk = gchalk.New(gchalk.ForceLevel(colorLevel))
+	return o
+}
+
 type interactiveShellOutput struct {
 	out    io.Writer
 	errOut *spinner
+	// chalk colors warnings, errors and end statuses
+	chalk *gchalk.Builder
 	// verbosity is the maximum V level that is printed to output
 	verbosity int
 	// level is the V level of this instance
@@ -53,7 +64,7 @@ func (o *interactiveShellOutput) Warn(msg string) {
 	if o.level > 0 {
 		msg += formatKeysAndValues(o.keysAndValues)
 	}
-	fmt.Fprintln(o.errOut, gchalk.Stderr.Yellow(msg))
+	fmt.Fprintln(o.errOut, o.chalk.Yellow(msg))
 }
 
 func (o *interactiveShellOutput) Warnf(format string, args ...interface{}) {
@@ -77,7 +88,7 @@ func (o *interactiveShellOutput) Error(err error, msg string) {
 	if o.level > 0 {
 		output += formatKeysAndValues(o.keysAndValues)
 	}
-	fmt.Fprintln(o.errOut, gchalk.Stderr.Red(output))
+	fmt.Fprintln(o.errOut, o.chalk.Red(output))
 }
 
 func (o *interactiveShellOutput) Errorf(err error, format string, args ...interface{}) {
@@ -134,12 +145,21 @@ func (o *interactiveShellOutput) EndOperationWithStatus(endStatus EndOperationSt
 	}
 	o.errOut.Stop()
 	fmt.Fprint(o.errOut, "\r")
-	endStatus.Fprintln(o.errOut, status)
+	o.fprintlnStatus(endStatus, status)
 	o.status = ""
 	o.gauge = nil
 	o.errOut.SetProgressGauge(nil)
 }
 
+// fprintlnStatus writes the end status of an operation, coloring built-in statuses with the output's color level.
+func (o *interactiveShellOutput) fprintlnStatus(endStatus EndOperationStatus, msg string) {
+	if s, ok := endStatus.(status); ok {
+		s.fprintlnWithChalk(o.errOut, o.chalk, "%s", msg)
+		return
+	}
+	endStatus.Fprintln(o.errOut, "%s", msg)
+}
+
 func (o *interactiveShellOutput) Result(result string) {
 	fmt.Fprintln(o.out, result)
 }
@@ -159,6 +179,7 @@ func (o *interactiveShellOutput) V(level int) Output {
 	return &interactiveShellOutput{
 		out:           o.out,
 		errOut:        o.errOut,
+		chalk:         o.chalk,
 		verbosity:     o.verbosity,
 		level:         level,
 		keysAndValues: o.keysAndValues,
@@ -169,6 +190,7 @@ func (o *interactiveShellOutput) WithValues(keysAndValues ...interface{}) Output
 	return &interactiveShellOutput{
 		out:           o.out,
 		errOut:        o.errOut,
+		chalk:         o.chalk,
 		verbosity:     o.verbosity,
 		level:         o.level,
 		keysAndValues: append(o.keysAndValues, keysAndValues...),
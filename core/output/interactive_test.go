@@ -490,3 +490,27 @@ func TestInteractiveShellOutput(t *testing.T) {
 		errOut.Reset()
 	})
 }
+
+func TestInteractiveShellOutputColorLevel(t *testing.T) {
+	origGchalkStderr := gchalk.Stderr
+	defer func() {
+		gchalk.Stderr = origGchalkStderr
+	}()
+	gchalk.Stderr = gchalk.New(gchalk.ForceLevel(gchalk.LevelNone))
+
+	errOut := bytes.Buffer{}
+	tOutput := output.NewInteractiveShellWithColorLevel(io.Discard, &errOut, 0, gchalk.LevelAnsi256)
+	tOutput.Warn("warning message")
+	assert.Equal(t, termYellow+"warning message"+termDefaultFg+"\n", errOut.String())
+	errOut.Reset()
+
+	tOutput.StartOperation("operation")
+	tOutput.EndOperationWithStatus(output.Failure())
+	assert.Contains(t, errOut.String(), termRed+"✗"+termDefaultFg+" operation\n")
+
+	// other outputs are not affected
+	errOut.Reset()
+	output.NewInteractiveShell(io.Discard, &errOut, 0).Warn("warning message")
+	assert.Equal(t, "warning message\n", errOut.String())
+	assert.Equal(t, gchalk.LevelNone, gchalk.Stderr.GetLevel())
+}
@@ -93,7 +93,7 @@ func (o *nonInteractiveShellOutput) ErrorWriter() io.Writer {
 }
 
 func (o *nonInteractiveShellOutput) StartOperation(status string) {
-	o.EndOperation(true)
+	o.EndOperationWithStatus(Success())
 
 	o.lock.Lock()
 	defer o.lock.Unlock()
@@ -103,7 +103,7 @@ func (o *nonInteractiveShellOutput) StartOperation(status string) {
 }
 
 func (o *nonInteractiveShellOutput) StartOperationWithProgress(gauge *ProgressGauge) {
-	o.EndOperation(true)
+	o.EndOperationWithStatus(Success())
 
 	o.lock.Lock()
 	defer o.lock.Unlock()
@@ -113,18 +113,11 @@ func (o *nonInteractiveShellOutput) StartOperationWithProgress(gauge *ProgressGa
 }
 
 func (o *nonInteractiveShellOutput) EndOperation(success bool) {
-	o.lock.Lock()
-	defer o.lock.Unlock()
-
-	if o.status == "" {
-		return
-	}
 	if success {
-		o.Infof(" ✓ %s", o.status)
+		o.EndOperationWithStatus(Success())
 	} else {
-		o.Infof(" ✗ %s", o.status)
+		o.EndOperationWithStatus(Failure())
 	}
-	o.status = ""
 }
 
 func (o *nonInteractiveShellOutput) EndOperationWithStatus(endStatus EndOperationStatus) {
@@ -134,7 +127,9 @@ func (o *nonInteractiveShellOutput) EndOperationWithStatus(endStatus EndOperatio
 	if o.status == "" {
 		return
 	}
-	endStatus.Fprintln(o.errOut, o.status)
+	fmt.Fprintln(o.errOut, formatExtended(
+		statusLevel(KindOf(endStatus)), plainStatusLine(endStatus, o.status), o.keysAndValues,
+	))
 	o.status = ""
 }
 
@@ -366,7 +366,7 @@ func TestNonInteractiveShellOutput(t *testing.T) {
 		assertEqualExceptTimestamp("<timestamp> INF  ✓ working", outputLines[2])
 		assertEqualExceptTimestamp("<timestamp> INF  • working...", outputLines[3])
 		assertEqualExceptTimestamp("<timestamp> ERR an error    err=<nil>", outputLines[4])
-		assertEqualExceptTimestamp("<timestamp> INF  ✗ working", outputLines[5])
+		assertEqualExceptTimestamp("<timestamp> ERR  ✗ working", outputLines[5])
 	})
 
 	t.Run("concurrent", func(t *testing.T) {
@@ -126,6 +126,9 @@ func (s *spinner) Start() {
 						suffix := s.suffix
 						if s.gauge.IsReady() {
 							suffix = s.gauge.String()
+						} else if s.gauge != nil {
+							// a gauge without capacity only shows its status, which may change while running
+							suffix = s.gauge.String() + " "
 						}
 						fmt.Fprintf(s.writer, s.frameFormat, s.prefix, frame, suffix)
 					}()
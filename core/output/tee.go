@@ -0,0 +1,120 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import "io"
+
+// NewTeeOutput returns an Output that writes to all given outputs, e.g. to the terminal and a log file.
+func NewTeeOutput(outputs ...Output) Output {
+	return teeOutput(outputs)
+}
+
+type teeOutput []Output
+
+func (o teeOutput) Info(msg string) {
+	for _, output := range o {
+		output.Info(msg)
+	}
+}
+
+func (o teeOutput) Infof(format string, args ...interface{}) {
+	for _, output := range o {
+		output.Infof(format, args...)
+	}
+}
+
+func (o teeOutput) InfoWriter() io.Writer {
+	return msgWriter(o.Info)
+}
+
+func (o teeOutput) Warn(msg string) {
+	for _, output := range o {
+		output.Warn(msg)
+	}
+}
+
+func (o teeOutput) Warnf(format string, args ...interface{}) {
+	for _, output := range o {
+		output.Warnf(format, args...)
+	}
+}
+
+func (o teeOutput) WarnWriter() io.Writer {
+	return msgWriter(o.Warn)
+}
+
+func (o teeOutput) Error(err error, msg string) {
+	for _, output := range o {
+		output.Error(err, msg)
+	}
+}
+
+func (o teeOutput) Errorf(err error, format string, args ...interface{}) {
+	for _, output := range o {
+		output.Errorf(err, format, args...)
+	}
+}
+
+func (o teeOutput) ErrorWriter() io.Writer {
+	return msgWriter(func(msg string) {
+		o.Error(nil, msg)
+	})
+}
+
+func (o teeOutput) StartOperation(status string) {
+	for _, output := range o {
+		output.StartOperation(status)
+	}
+}
+
+func (o teeOutput) StartOperationWithProgress(gauge *ProgressGauge) {
+	for _, output := range o {
+		output.StartOperationWithProgress(gauge)
+	}
+}
+
+func (o teeOutput) EndOperation(success bool) {
+	for _, output := range o {
+		output.EndOperation(success) //nolint:staticcheck // Delegating deprecated method.
+	}
+}
+
+func (o teeOutput) EndOperationWithStatus(endStatus EndOperationStatus) {
+	for _, output := range o {
+		output.EndOperationWithStatus(endStatus)
+	}
+}
+
+func (o teeOutput) Result(result string) {
+	for _, output := range o {
+		output.Result(result)
+	}
+}
+
+func (o teeOutput) ResultWriter() io.Writer {
+	writers := make([]io.Writer, 0, len(o))
+	for _, output := range o {
+		writers = append(writers, output.ResultWriter())
+	}
+	return io.MultiWriter(writers...)
+}
+
+func (o teeOutput) V(level int) Output {
+	result := make(teeOutput, 0, len(o))
+	for _, output := range o {
+		result = append(result, output.V(level))
+	}
+	return result
+}
+
+func (o teeOutput) WithValues(keysAndValues ...interface{}) Output {
+	result := make(teeOutput, 0, len(o))
+	for _, output := range o {
+		result = append(result, output.WithValues(keysAndValues...))
+	}
+	return result
+}
+
+// Convention used to verify, at compile time, that teeOutput implements the Output interface.
+var _ Output = teeOutput{}
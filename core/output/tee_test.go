@@ -0,0 +1,45 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package output_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+func TestTeeOutput(t *testing.T) {
+	out1, errOut1 := bytes.Buffer{}, bytes.Buffer{}
+	out2, errOut2 := bytes.Buffer{}, bytes.Buffer{}
+	o := output.NewTeeOutput(
+		output.NewNonInteractiveShell(&out1, &errOut1, 0),
+		output.NewNonInteractiveShell(&out2, &errOut2, 1),
+	)
+
+	o.Info("info")
+	o.V(1).Info("verbose")
+	o.WithValues("key", "value").Warn("warning")
+	o.Error(errors.New("error"), "failed")
+	o.StartOperation("working")
+	o.EndOperationWithStatus(output.Success())
+	o.Result("result")
+	fmt.Fprintln(o.ResultWriter(), "written result")
+
+	assert.Equal(t, "result\nwritten result\n", out1.String())
+	assert.Equal(t, out1.String(), out2.String())
+
+	assert.NotContains(t, errOut1.String(), "verbose")
+	assert.Contains(t, errOut2.String(), "INF verbose")
+	for _, errOut := range []string{errOut1.String(), errOut2.String()} {
+		assert.Contains(t, errOut, "INF info")
+		assert.Contains(t, errOut, "WRN warning    key=value")
+		assert.Contains(t, errOut, "ERR failed    err=error")
+		assert.Contains(t, errOut, "INF  ✓ working")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores the Spec of plugins, so plugins do not have to be executed for discovery every time. An entry is used
+// while the plugin executable's size, modification time and inode are unchanged. If only the modification time or
+// inode changed (e.g. the executable was touched or copied), the content hash decides, so looking up an unchanged
+// plugin never reads the executable.
+type Cache struct {
+	dir string
+}
+
+// cacheEntry is stored as JSON file per plugin path.
+type cacheEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Inode is 0 on platforms without inodes.
+	Inode  uint64 `json:"inode,omitempty"`
+	SHA256 string `json:"sha256"`
+	Spec   Spec   `json:"spec"`
+}
+
+// NewCache creates a cache storing entries in the given directory.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// DefaultCacheDir returns the directory the plugin cache of the given application is stored in by default, within the
+// user's cache directory.
+func DefaultCacheDir(appName string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName, "plugins"), nil
+}
+
+// Dir returns the directory the cache is stored in.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the cached Spec of the plugin executable at path. It returns false if there is no entry or the entry is
+// outdated.
+func (c *Cache) Get(path string) (Spec, bool) {
+	data, err := os.ReadFile(c.entryPath(path))
+	if err != nil {
+		return Spec{}, false
+	}
+	entry := cacheEntry{}
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Path != path {
+		return Spec{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != entry.Size {
+		return Spec{}, false
+	}
+	if info.ModTime().Equal(entry.ModTime) && fileInode(info) == entry.Inode {
+		return entry.Spec, true
+	}
+	// the executable may have been touched or copied without changing its content
+	digest, err := fileDigest(path)
+	if err != nil || digest != entry.SHA256 {
+		return Spec{}, false
+	}
+	// the content is only hashed again after the next change, failing to update the entry is not a problem
+	_ = c.write(path, info, digest, entry.Spec)
+	return entry.Spec, true
+}
+
+// Put stores the Spec of the plugin executable at path.
+func (c *Cache) Put(path string, spec Spec) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+	return c.write(path, info, digest, spec)
+}
+
+func (c *Cache) write(path string, info os.FileInfo, digest string, spec Spec) error {
+	data, err := json.Marshal(cacheEntry{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Inode:   fileInode(info),
+		SHA256:  digest,
+		Spec:    spec,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	// concurrent readers must never see a partially written entry
+	return writeFileAtomically(c.entryPath(path), data)
+}
+
+// Clear removes all entries.
+func (c *Cache) Clear() error {
+	err := os.RemoveAll(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// get is like Get, but can be called on a nil cache.
+func (c *Cache) get(path string) (Spec, bool) {
+	if c == nil {
+		return Spec{}, false
+	}
+	return c.Get(path)
+}
+
+// put is like Put, but can be called on a nil cache.
+func (c *Cache) put(path string, spec Spec) error {
+	if c == nil {
+		return nil
+	}
+	return c.Put(path, spec)
+}
+
+// entryPath returns the file the entry for the plugin executable at path is stored in.
+func (c *Cache) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
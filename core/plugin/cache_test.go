@@ -0,0 +1,109 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dkp-example")
+	require.NoError(t, os.WriteFile(path, []byte("version 1"), 0o700))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	cache := plugin.NewCache(filepath.Join(dir, "cache"))
+	_, ok := cache.Get(path)
+	assert.False(t, ok)
+
+	spec := plugin.Spec{Commands: plugin.CommandSpec{Use: "dkp-example"}}
+	require.NoError(t, cache.Put(path, spec))
+	cached, ok := cache.Get(path)
+	require.True(t, ok)
+	assert.Equal(t, spec, cached)
+
+	// touched without changing the content
+	require.NoError(t, os.Chtimes(path, modTime.Add(time.Second), modTime.Add(time.Second)))
+	cached, ok = cache.Get(path)
+	require.True(t, ok)
+	assert.Equal(t, spec, cached)
+
+	// replaced by a file with the same size and modification time, different content
+	replacement := filepath.Join(dir, "replacement")
+	require.NoError(t, os.WriteFile(replacement, []byte("version 2"), 0o700))
+	require.NoError(t, os.Chtimes(replacement, modTime.Add(time.Second), modTime.Add(time.Second)))
+	require.NoError(t, os.Rename(replacement, path))
+	_, ok = cache.Get(path)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Put(path, spec))
+	_, ok = cache.Get(path)
+	assert.True(t, ok)
+
+	// different size
+	require.NoError(t, os.WriteFile(path, []byte("version 10"), 0o700))
+	_, ok = cache.Get(path)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Clear())
+	assert.NoDirExists(t, cache.Dir())
+	require.NoError(t, cache.Clear())
+}
+
+func TestManagerCache(t *testing.T) {
+	dir := t.TempDir()
+	invocations := filepath.Join(dir, "invocations")
+	script := fmt.Sprintf(`#!/bin/sh
+echo invoked >> %q
+echo '{"commands": {"use": "dkp-script", "sub_commands": [{"use": "hello", "runnable": true}]}}'
+`, invocations)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-script"), []byte(script), 0o700))
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	manager.Cache = plugin.NewCache(filepath.Join(t.TempDir(), "cache"))
+
+	countInvocations := func() int {
+		data, err := os.ReadFile(invocations)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return strings.Count(string(data), "invoked")
+	}
+
+	// completion does not execute plugins that are not cached yet
+	manager.CacheOnly = true
+	plugins, errs := manager.Discover(context.Background())
+	require.Empty(t, errs)
+	assert.Empty(t, plugins)
+	assert.Equal(t, 0, countInvocations())
+
+	manager.CacheOnly = false
+	for i := 0; i < 2; i++ {
+		plugins, errs = manager.Discover(context.Background())
+		require.Empty(t, errs)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, "hello", plugins[0].Spec.Commands.SubCommands[0].Use)
+		assert.Equal(t, 1, countInvocations())
+	}
+
+	manager.CacheOnly = true
+	plugins, errs = manager.Discover(context.Background())
+	require.Empty(t, errs)
+	assert.Len(t, plugins, 1)
+	assert.Equal(t, 1, countInvocations())
+}
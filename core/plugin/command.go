@@ -4,9 +4,24 @@
 package plugin
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Flag annotations cobra uses for flag groups (see cobra.Command.MarkFlagsMutuallyExclusive and
+// cobra.Command.MarkFlagsRequiredTogether).
+const (
+	mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+	requiredTogetherAnnotation  = "cobra_annotation_required_if_others_set"
 )
 
+// argsAnnotation is the command annotation holding the ArgsSpec set with SetArgsSpec, as JSON.
+const argsAnnotation = "dkp_cli_args"
+
 // CommandSpec is a serializable object that has all the information that describes a cobra.Command.
 type CommandSpec struct {
 	Use                        string            `json:"use"`
@@ -24,6 +39,17 @@ type CommandSpec struct {
 	DisableFlagsInUseLine      bool              `json:"disable_flags_in_use_line,omitempty"`
 	DisableSuggestions         bool              `json:"disable_suggestions,omitempty"`
 	SuggestionsMinimumDistance int               `json:"suggestions_minimum_distance,omitempty"`
+	ValidArgs                  []string          `json:"valid_args,omitempty"`
+	ArgAliases                 []string          `json:"arg_aliases,omitempty"`
+	GroupID                    string            `json:"group_id,omitempty"`
+	Groups                     []GroupSpec       `json:"groups,omitempty"`
+
+	// Args describes the positional arguments accepted by the command. Any arguments are accepted if nil.
+	Args *ArgsSpec `json:"args,omitempty"`
+	// MutuallyExclusiveFlags are groups of flags of which at most one may be set.
+	MutuallyExclusiveFlags [][]string `json:"mutually_exclusive_flags,omitempty"`
+	// RequiredTogetherFlags are groups of flags of which either all or none must be set.
+	RequiredTogetherFlags [][]string `json:"required_together_flags,omitempty"`
 
 	LocalFlags      []FlagSpec    `json:"local_flags,omitempty"`
 	PersistentFlags []FlagSpec    `json:"persistent_flags,omitempty"`
@@ -31,6 +57,22 @@ type CommandSpec struct {
 	SubCommands     []CommandSpec `json:"sub_commands,omitempty"`
 }
 
+// GroupSpec describes a cobra.Group, a group of subcommands in the help output.
+type GroupSpec struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ArgsSpec describes the positional arguments accepted by a command.
+type ArgsSpec struct {
+	// Min is the minimum number of arguments.
+	Min int `json:"min"`
+	// Max is the maximum number of arguments, -1 for no limit.
+	Max int `json:"max"`
+	// OnlyValid is true if only the command's ValidArgs are accepted.
+	OnlyValid bool `json:"only_valid,omitempty"`
+}
+
 // SpecFromCommand creates a CommandSpec describing a cobra.Command.
 func SpecFromCommand(cmd *cobra.Command) CommandSpec {
 	result := CommandSpec{
@@ -49,15 +91,26 @@ func SpecFromCommand(cmd *cobra.Command) CommandSpec {
 		DisableFlagsInUseLine:      cmd.DisableFlagsInUseLine,
 		DisableSuggestions:         cmd.DisableSuggestions,
 		SuggestionsMinimumDistance: cmd.SuggestionsMinimumDistance,
+		ValidArgs:                  cmd.ValidArgs,
+		ArgAliases:                 cmd.ArgAliases,
+		GroupID:                    cmd.GroupID,
+
+		Args:                   argsSpecFromCommand(cmd),
+		MutuallyExclusiveFlags: flagGroupsFromCommand(cmd, mutuallyExclusiveAnnotation),
+		RequiredTogetherFlags:  flagGroupsFromCommand(cmd, requiredTogetherAnnotation),
 
 		LocalFlags:      SpecsFromFlagset(cmd.LocalNonPersistentFlags()),
 		PersistentFlags: SpecsFromFlagset(cmd.PersistentFlags()),
 		Runnable:        cmd.Runnable(),
 	}
 
+	for _, group := range cmd.Groups() {
+		result.Groups = append(result.Groups, GroupSpec{ID: group.ID, Title: group.Title})
+	}
+
 	for _, subCmd := range cmd.Commands() {
 		switch subCmd.Name() {
-		case DiscoveryCommandName, "completion":
+		case DiscoveryCommandName, SpecDiffCommandName, "completion":
 			continue
 		}
 		if subCmd.Annotations["exclude-from-dkp-cli"] == "true" {
@@ -86,6 +139,15 @@ func (c CommandSpec) ToCommand(runOverride func(*cobra.Command, []string) error)
 		DisableFlagsInUseLine:      c.DisableFlagsInUseLine,
 		DisableSuggestions:         c.DisableSuggestions,
 		SuggestionsMinimumDistance: c.SuggestionsMinimumDistance,
+		ValidArgs:                  c.ValidArgs,
+		ArgAliases:                 c.ArgAliases,
+		GroupID:                    c.GroupID,
+	}
+	if c.Args != nil {
+		SetArgsSpec(command, *c.Args)
+	}
+	for _, group := range c.Groups {
+		command.AddGroup(&cobra.Group{ID: group.ID, Title: group.Title})
 	}
 
 	for _, flagSpec := range c.PersistentFlags {
@@ -94,6 +156,19 @@ func (c CommandSpec) ToCommand(runOverride func(*cobra.Command, []string) error)
 	for _, flagSpec := range c.LocalFlags {
 		command.Flags().AddFlag(flagSpec.ToFlag())
 	}
+	// file names and directories are completed by cobra based on the flag annotations
+	for _, flagSpec := range append(append([]FlagSpec{}, c.PersistentFlags...), c.LocalFlags...) {
+		if flagSpec.Completion != nil && len(flagSpec.Completion.Values) > 0 {
+			_ = command.RegisterFlagCompletionFunc(flagSpec.Name, completeValues(flagSpec.Completion.Values))
+		}
+	}
+	// the flag groups are usually restored with the flag annotations already
+	for _, group := range c.MutuallyExclusiveFlags {
+		addFlagGroup(command, mutuallyExclusiveAnnotation, group)
+	}
+	for _, group := range c.RequiredTogetherFlags {
+		addFlagGroup(command, requiredTogetherAnnotation, group)
+	}
 
 	for _, subSpec := range c.SubCommands {
 		command.AddCommand(subSpec.ToCommand(runOverride))
@@ -104,3 +179,114 @@ func (c CommandSpec) ToCommand(runOverride func(*cobra.Command, []string) error)
 	}
 	return command
 }
+
+// SetArgsSpec makes cmd accept the described positional arguments, using cobra's validators (e.g. cobra.ExactArgs), and
+// records the description in an annotation, so it is part of the command's spec:
+//
+//	plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 1, Max: 1})
+//
+// Args validators are functions that are not executed when creating specs, so only cobra.NoArgs, cobra.ArbitraryArgs
+// and cobra.OnlyValidArgs are described without this.
+func SetArgsSpec(cmd *cobra.Command, args ArgsSpec) {
+	data, _ := json.Marshal(args)
+	annotations := make(map[string]string, len(cmd.Annotations)+1)
+	for key, value := range cmd.Annotations {
+		annotations[key] = value
+	}
+	annotations[argsAnnotation] = string(data)
+	cmd.Annotations = annotations
+	cmd.Args = args.validator()
+}
+
+// argsSpecFromCommand describes the positional arguments accepted by cmd: the ArgsSpec set with SetArgsSpec, or the
+// cobra validators without parameters. Other validators are not described.
+func argsSpecFromCommand(cmd *cobra.Command) *ArgsSpec {
+	if data, ok := cmd.Annotations[argsAnnotation]; ok {
+		spec := &ArgsSpec{}
+		if err := json.Unmarshal([]byte(data), spec); err != nil {
+			return nil
+		}
+		return spec
+	}
+	if cmd.Args == nil {
+		return nil
+	}
+	switch reflect.ValueOf(cmd.Args).Pointer() {
+	case reflect.ValueOf(cobra.NoArgs).Pointer():
+		return &ArgsSpec{Min: 0, Max: 0}
+	case reflect.ValueOf(cobra.ArbitraryArgs).Pointer():
+		return &ArgsSpec{Min: 0, Max: -1}
+	case reflect.ValueOf(cobra.OnlyValidArgs).Pointer():
+		return &ArgsSpec{Min: 0, Max: -1, OnlyValid: len(cmd.ValidArgs) > 0}
+	}
+	return nil
+}
+
+// validator returns an Args validator accepting the described arguments, using cobra's validators so error messages
+// are the same.
+func (a ArgsSpec) validator() cobra.PositionalArgs {
+	var validator cobra.PositionalArgs
+	switch {
+	case a.Max < 0 && a.Min <= 0:
+		validator = cobra.ArbitraryArgs
+	case a.Max < 0:
+		validator = cobra.MinimumNArgs(a.Min)
+	case a.Max == 0:
+		validator = cobra.NoArgs
+	case a.Min == a.Max:
+		validator = cobra.ExactArgs(a.Min)
+	case a.Min <= 0:
+		validator = cobra.MaximumNArgs(a.Max)
+	default:
+		validator = cobra.RangeArgs(a.Min, a.Max)
+	}
+	if a.OnlyValid {
+		return cobra.MatchAll(cobra.OnlyValidArgs, validator)
+	}
+	return validator
+}
+
+// flagGroupsFromCommand returns the flag groups of the flags defined by cmd with the given annotation.
+func flagGroupsFromCommand(cmd *cobra.Command, annotation string) [][]string {
+	var groups [][]string
+	seen := map[string]bool{}
+	collect := func(flag *pflag.Flag) {
+		for _, group := range flag.Annotations[annotation] {
+			if !seen[group] {
+				seen[group] = true
+				groups = append(groups, strings.Split(group, " "))
+			}
+		}
+	}
+	cmd.LocalNonPersistentFlags().VisitAll(collect)
+	cmd.PersistentFlags().VisitAll(collect)
+	return groups
+}
+
+// addFlagGroup adds the flag group annotation to the flags of the group defined by cmd, unless it is there already.
+// Unlike cobra.Command.MarkFlagsMutuallyExclusive, flags that are not defined by cmd (e.g. inherited ones) are ignored.
+func addFlagGroup(cmd *cobra.Command, annotation string, group []string) {
+	value := strings.Join(group, " ")
+	for _, name := range group {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			flag = cmd.PersistentFlags().Lookup(name)
+		}
+		if flag == nil || containsString(flag.Annotations[annotation], value) {
+			continue
+		}
+		if flag.Annotations == nil {
+			flag.Annotations = map[string][]string{}
+		}
+		flag.Annotations[annotation] = append(flag.Annotations[annotation], value)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ package plugin_test
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -158,3 +159,93 @@ func assertCommandsProduceSameCompletion(t *testing.T, expected, actual *cobra.C
 	actual.GenPowerShellCompletion(&actualOutput)
 	assert.Equal(t, expectedOutput.String(), actualOutput.String())
 }
+
+func TestCommandSpecConstraints(t *testing.T) {
+	setArgs := map[string]func(cmd *cobra.Command){
+		"no args":        func(cmd *cobra.Command) { cmd.Args = cobra.NoArgs },
+		"arbitrary args": func(cmd *cobra.Command) { cmd.Args = cobra.ArbitraryArgs },
+		"only valid":     func(cmd *cobra.Command) { cmd.Args = cobra.OnlyValidArgs },
+		"exact args":     func(cmd *cobra.Command) { plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 2, Max: 2}) },
+		"minimum args":   func(cmd *cobra.Command) { plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 1, Max: -1}) },
+		"maximum args":   func(cmd *cobra.Command) { plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 0, Max: 3}) },
+		"range args":     func(cmd *cobra.Command) { plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 1, Max: 2}) },
+		"exact valid": func(cmd *cobra.Command) {
+			plugin.SetArgsSpec(cmd, plugin.ArgsSpec{Min: 1, Max: 1, OnlyValid: true})
+		},
+	}
+	argLists := [][]string{{}, {"a"}, {"a", "b"}, {"a", "b", "c"}, {"a", "b", "c", "d"}, {"x"}, {"a", "x"}}
+
+	for name, set := range setArgs {
+		set := set
+		t.Run(name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "cmd", ValidArgs: []string{"a\tthe a", "b", "c", "d"}}
+			set(cmd)
+			cmdFromSpec := plugin.SpecFromCommand(cmd).ToCommand(nil)
+			for _, args := range argLists {
+				assert.Equal(t, cmd.ValidateArgs(args), cmdFromSpec.ValidateArgs(args), "%q", args)
+			}
+			// the spec survives another round trip
+			assert.Equal(t, plugin.SpecFromCommand(cmd).Args, plugin.SpecFromCommand(cmdFromSpec).Args)
+		})
+	}
+
+	t.Run("custom validator", func(t *testing.T) {
+		called := false
+		cmd := &cobra.Command{Use: "cmd", Args: func(cmd *cobra.Command, args []string) error {
+			called = true
+			return nil
+		}}
+		assert.Nil(t, plugin.SpecFromCommand(cmd).Args)
+		assert.False(t, called, "validators must not be executed")
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		newCmd := func() *cobra.Command {
+			cmd := &cobra.Command{Use: "cmd", Run: func(cmd *cobra.Command, args []string) {}}
+			cmd.Flags().String("required", "", "usage")
+			cmd.Flags().String("a", "", "usage")
+			cmd.Flags().String("b", "", "usage")
+			cmd.Flags().String("c", "", "usage")
+			require.NoError(t, cmd.MarkFlagRequired("required"))
+			cmd.MarkFlagsMutuallyExclusive("a", "b")
+			cmd.MarkFlagsRequiredTogether("b", "c")
+			return cmd
+		}
+
+		spec := plugin.SpecFromCommand(newCmd())
+		assert.Equal(t, [][]string{{"a", "b"}}, spec.MutuallyExclusiveFlags)
+		assert.Equal(t, [][]string{{"b", "c"}}, spec.RequiredTogetherFlags)
+		assert.True(t, spec.LocalFlags[3].Required)
+
+		// without annotations, the constraints are restored from the explicit fields
+		for i := range spec.LocalFlags {
+			spec.LocalFlags[i].Annotations = nil
+		}
+		runE := func(cmd *cobra.Command, args []string) error { return nil }
+		for _, args := range [][]string{
+			{},
+			{"--required=x"},
+			{"--required=x", "--a=x", "--b=x", "--c=x"},
+			{"--required=x", "--b=x"},
+			{"--required=x", "--b=x", "--c=x"},
+		} {
+			cmd, cmdFromSpec := newCmd(), spec.ToCommand(runE)
+			cmd.SetArgs(args)
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmdFromSpec.SetArgs(args)
+			cmdFromSpec.SetOut(io.Discard)
+			cmdFromSpec.SetErr(io.Discard)
+			assert.Equal(t, cmd.Execute(), cmdFromSpec.Execute(), "%q", args)
+		}
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "cmd", ArgAliases: []string{"alias"}}
+		cmd.AddGroup(&cobra.Group{ID: "first", Title: "First Commands:"})
+		cmd.AddCommand(&cobra.Command{Use: "one", GroupID: "first", Run: func(cmd *cobra.Command, args []string) {}})
+		cmd.AddCommand(&cobra.Command{Use: "two", Run: func(cmd *cobra.Command, args []string) {}})
+		testCommandSpec(t, cmd)
+		assert.Equal(t, []string{"alias"}, plugin.SpecFromCommand(cmd).ArgAliases)
+	})
+}
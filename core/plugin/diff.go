@@ -0,0 +1,372 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const SpecDiffCommandName = "_plugin_spec_diff"
+
+// Change is a difference between two versions of a Spec.
+type Change struct {
+	// Breaking is true if the change may break existing invocations, e.g. in scripts.
+	Breaking bool
+	// Command is the path of the affected command without the root command, e.g. "create cluster".
+	Command string
+	// Flag is the name of the affected flag, if any.
+	Flag string
+	// Description describes the change, e.g. "flag was removed".
+	Description string
+}
+
+func (c Change) String() string {
+	subject := c.Command
+	if subject == "" {
+		subject = "root command"
+	}
+	if c.Flag != "" {
+		subject += " --" + c.Flag
+	}
+	return fmt.Sprintf("%s: %s", subject, c.Description)
+}
+
+// Changes is a list of changes.
+type Changes []Change
+
+// Breaking returns the breaking changes.
+func (c Changes) Breaking() Changes {
+	result := Changes{}
+	for _, change := range c {
+		if change.Breaking {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// Write writes the changes to w, one per line, breaking changes first.
+func (c Changes) Write(w io.Writer) error {
+	for _, breaking := range []bool{true, false} {
+		for _, change := range c {
+			if change.Breaking != breaking {
+				continue
+			}
+			kind := "non-breaking"
+			if breaking {
+				kind = "BREAKING"
+			}
+			if _, err := fmt.Fprintf(w, "%-12s %s\n", kind, change); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DiffSpecs compares two versions of a Spec and returns the changes from oldSpec to newSpec, e.g. to detect breaking
+// changes of a CLI before it is released:
+//   - removed or renamed commands and aliases, commands that are not runnable anymore and fewer accepted arguments
+//   - removed flags, changed flag types, removed or changed shorthands, newly required flags and shorthand collisions
+//     with inherited flags
+//   - added commands and flags, deprecations and changed defaults are not breaking.
+//
+// Flags are compared including the persistent flags inherited from parent commands, so moving a flag to a parent
+// command is not a change.
+func DiffSpecs(oldSpec, newSpec Spec) Changes {
+	changes := Changes{}
+	diffCommands(&changes, "", oldSpec.Commands, newSpec.Commands, nil, nil)
+	return changes
+}
+
+func diffCommands(changes *Changes, path string, oldCmd, newCmd CommandSpec, oldInherited, newInherited []FlagSpec) {
+	add := func(breaking bool, flag, format string, args ...interface{}) {
+		*changes = append(*changes, Change{
+			Breaking: breaking, Command: path, Flag: flag, Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if oldCmd.Runnable && !newCmd.Runnable {
+		add(true, "", "command is not runnable anymore")
+	}
+	for _, alias := range oldCmd.Aliases {
+		if !containsString(newCmd.Aliases, alias) {
+			add(true, "", "alias %q was removed", alias)
+		}
+	}
+	if oldCmd.Deprecated == "" && newCmd.Deprecated != "" {
+		add(false, "", "command was deprecated: %s", newCmd.Deprecated)
+	}
+	if oldCmd.Runnable && newCmd.Runnable {
+		diffArgs(add, oldCmd, newCmd)
+	}
+
+	oldFlags := effectiveFlags(oldCmd, oldInherited)
+	newFlags := effectiveFlags(newCmd, newInherited)
+	// changes of flags inherited in both versions are reported for the parent command only
+	newInheritedFlags := effectiveFlags(CommandSpec{}, newInherited)
+	for name := range effectiveFlags(CommandSpec{}, oldInherited) {
+		if _, ok := newInheritedFlags[name]; ok {
+			delete(oldFlags, name)
+			delete(newFlags, name)
+		}
+	}
+	diffFlags(add, oldFlags, newFlags)
+	// only collisions that are new, existing ones are not a change
+	oldCollisions := map[string]bool{}
+	for _, collision := range shorthandCollisions(oldCmd, oldInherited) {
+		oldCollisions[collision.String()] = true
+	}
+	for _, collision := range shorthandCollisions(newCmd, newInherited) {
+		if !oldCollisions[collision.String()] {
+			add(true, "", "%s", collision)
+		}
+	}
+
+	oldSubCmds := commandsByName(oldCmd.SubCommands)
+	newSubCmds := commandsByName(newCmd.SubCommands)
+	for _, name := range sortedKeys(oldSubCmds) {
+		subPath := strings.TrimSpace(path + " " + name)
+		newSubCmd, ok := newSubCmds[name]
+		if !ok {
+			newSubCmd, ok = commandWithAlias(newCmd.SubCommands, name)
+			if ok {
+				*changes = append(*changes, Change{Command: subPath, Description: fmt.Sprintf(
+					"command was renamed to %q, the old name is an alias", commandName(newSubCmd.Use),
+				)})
+			}
+		}
+		if !ok {
+			*changes = append(*changes, Change{Breaking: true, Command: subPath, Description: "command was removed"})
+			continue
+		}
+		diffCommands(changes, subPath, oldSubCmds[name], newSubCmd,
+			append(append([]FlagSpec{}, oldInherited...), oldCmd.PersistentFlags...),
+			append(append([]FlagSpec{}, newInherited...), newCmd.PersistentFlags...))
+	}
+	for _, name := range sortedKeys(newSubCmds) {
+		if _, ok := oldSubCmds[name]; ok {
+			continue
+		}
+		renamed := false
+		for _, alias := range newSubCmds[name].Aliases {
+			_, renamed = oldSubCmds[alias]
+			if renamed {
+				break
+			}
+		}
+		if !renamed {
+			*changes = append(*changes, Change{
+				Command: strings.TrimSpace(path + " " + name), Description: "command was added",
+			})
+		}
+	}
+}
+
+func diffArgs(add func(bool, string, string, ...interface{}), oldCmd, newCmd CommandSpec) {
+	oldArgs, newArgs := ArgsSpec{Min: 0, Max: -1}, ArgsSpec{Min: 0, Max: -1}
+	if oldCmd.Args != nil {
+		oldArgs = *oldCmd.Args
+	}
+	if newCmd.Args != nil {
+		newArgs = *newCmd.Args
+	}
+	if newArgs.Min > oldArgs.Min {
+		add(true, "", "requires at least %d arguments instead of %d", newArgs.Min, oldArgs.Min)
+	}
+	if newArgs.Max >= 0 && (oldArgs.Max < 0 || newArgs.Max < oldArgs.Max) {
+		add(true, "", "accepts at most %d arguments instead of %s", newArgs.Max, describeMaxArgs(oldArgs.Max))
+	}
+	validArgs := validArgNames(newCmd.ValidArgs)
+	switch {
+	case newArgs.OnlyValid && !oldArgs.OnlyValid:
+		add(true, "", "only accepts the arguments %s", strings.Join(validArgs, ", "))
+	case newArgs.OnlyValid:
+		for _, arg := range validArgNames(oldCmd.ValidArgs) {
+			if !containsString(validArgs, arg) {
+				add(true, "", "argument %q is not accepted anymore", arg)
+			}
+		}
+	}
+}
+
+func describeMaxArgs(max int) string {
+	if max < 0 {
+		return "any number"
+	}
+	return fmt.Sprint(max)
+}
+
+func validArgNames(validArgs []string) []string {
+	result := make([]string, 0, len(validArgs))
+	for _, arg := range validArgs {
+		result = append(result, strings.SplitN(arg, "\t", 2)[0]) //nolint:gomnd // Argument and description.
+	}
+	return result
+}
+
+func diffFlags(add func(bool, string, string, ...interface{}), oldFlags, newFlags map[string]FlagSpec) {
+	for _, name := range sortedKeys(oldFlags) {
+		oldFlag := oldFlags[name]
+		newFlag, ok := newFlags[name]
+		if !ok {
+			add(true, name, "flag was removed")
+			continue
+		}
+		if oldFlag.Type != newFlag.Type {
+			add(true, name, "type changed from %s to %s", oldFlag.Type, newFlag.Type)
+		}
+		switch {
+		case oldFlag.Shorthand != "" && newFlag.Shorthand == "":
+			add(true, name, "shorthand -%s was removed", oldFlag.Shorthand)
+		case oldFlag.Shorthand != "" && oldFlag.Shorthand != newFlag.Shorthand:
+			add(true, name, "shorthand changed from -%s to -%s", oldFlag.Shorthand, newFlag.Shorthand)
+		}
+		if !oldFlag.required() && newFlag.required() {
+			add(true, name, "flag is required now")
+		}
+		if oldFlag.NoOptDefaultValue != "" && newFlag.NoOptDefaultValue == "" {
+			add(true, name, "flag requires a value now")
+		}
+		if oldFlag.DefaultValue != newFlag.DefaultValue {
+			add(false, name, "default changed from %q to %q", oldFlag.DefaultValue, newFlag.DefaultValue)
+		}
+		if oldFlag.Deprecated == "" && newFlag.Deprecated != "" {
+			add(false, name, "flag was deprecated: %s", newFlag.Deprecated)
+		}
+	}
+	for _, name := range sortedKeys(newFlags) {
+		if _, ok := oldFlags[name]; ok {
+			continue
+		}
+		if newFlags[name].required() {
+			add(true, name, "required flag was added")
+		} else {
+			add(false, name, "flag was added")
+		}
+	}
+}
+
+// effectiveFlags returns the flags of the command including inherited persistent flags, by name.
+func effectiveFlags(cmd CommandSpec, inherited []FlagSpec) map[string]FlagSpec {
+	result := map[string]FlagSpec{}
+	for _, flags := range [][]FlagSpec{inherited, cmd.PersistentFlags, cmd.LocalFlags} {
+		for _, flag := range flags {
+			result[flag.Name] = flag
+		}
+	}
+	return result
+}
+
+type shorthandCollision struct {
+	shorthand string
+	flags     []string
+}
+
+func (c shorthandCollision) String() string {
+	return fmt.Sprintf("shorthand -%s is used by flags %s", c.shorthand, strings.Join(c.flags, ", "))
+}
+
+// shorthandCollisions returns the shorthands used by multiple flags of the command (including inherited flags). cobra
+// panics when running such commands.
+func shorthandCollisions(cmd CommandSpec, inherited []FlagSpec) []shorthandCollision {
+	flagsByShorthand := map[string][]string{}
+	for _, flags := range [][]FlagSpec{inherited, cmd.PersistentFlags, cmd.LocalFlags} {
+		for _, flag := range flags {
+			if flag.Shorthand != "" && !containsString(flagsByShorthand[flag.Shorthand], flag.Name) {
+				flagsByShorthand[flag.Shorthand] = append(flagsByShorthand[flag.Shorthand], flag.Name)
+			}
+		}
+	}
+	result := []shorthandCollision{}
+	for _, shorthand := range sortedKeys(flagsByShorthand) {
+		if flags := flagsByShorthand[shorthand]; len(flags) > 1 {
+			result = append(result, shorthandCollision{shorthand: shorthand, flags: flags})
+		}
+	}
+	return result
+}
+
+func commandsByName(cmds []CommandSpec) map[string]CommandSpec {
+	result := map[string]CommandSpec{}
+	for _, cmd := range cmds {
+		result[commandName(cmd.Use)] = cmd
+	}
+	return result
+}
+
+func commandWithAlias(cmds []CommandSpec, alias string) (CommandSpec, bool) {
+	for _, cmd := range cmds {
+		if containsString(cmd.Aliases, alias) {
+			return cmd, true
+		}
+	}
+	return CommandSpec{}, false
+}
+
+// commandName returns the name of a command like cobra.Command.Name.
+func commandName(use string) string {
+	name := use
+	if i := strings.Index(name, " "); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewSpecDiffCommand creates a hidden command comparing two Spec JSON files, e.g. the discovery output of the last
+// release and of the current build (see DiffSpecs). It fails if there are breaking changes.
+func NewSpecDiffCommand(output io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:    SpecDiffCommandName + " OLD_SPEC_FILE NEW_SPEC_FILE",
+		Args:   cobra.ExactArgs(2), //nolint:gomnd // Old and new spec.
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldSpec, err := readSpec(args[0])
+			if err != nil {
+				return err
+			}
+			newSpec, err := readSpec(args[1])
+			if err != nil {
+				return err
+			}
+
+			changes := DiffSpecs(oldSpec, newSpec)
+			if err := changes.Write(output); err != nil {
+				return err
+			}
+			if breaking := len(changes.Breaking()); breaking > 0 {
+				return fmt.Errorf("found %d breaking changes", breaking)
+			}
+			return nil
+		},
+	}
+}
+
+func readSpec(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+	spec := Spec{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return Spec{}, fmt.Errorf("invalid spec %s: %w", path, err)
+	}
+	return spec, nil
+}
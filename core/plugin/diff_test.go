@@ -0,0 +1,141 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func newDiffTestCommand(modify func(rootCmd, getCmd, deleteCmd *cobra.Command)) plugin.Spec {
+	rootCmd := &cobra.Command{Use: "example"}
+	rootCmd.PersistentFlags().StringP("namespace", "n", "default", "namespace")
+	getCmd := &cobra.Command{Use: "get NAME", Run: func(*cobra.Command, []string) {}}
+	plugin.SetArgsSpec(getCmd, plugin.ArgsSpec{Min: 0, Max: 1})
+	getCmd.Flags().StringP("output", "o", "table", "output format")
+	getCmd.Flags().Int("limit", 0, "limit")
+	deleteCmd := &cobra.Command{Use: "delete", Aliases: []string{"rm"}, Run: func(*cobra.Command, []string) {}}
+	deleteCmd.Flags().Bool("force", false, "force")
+	rootCmd.AddCommand(getCmd, deleteCmd)
+	if modify != nil {
+		modify(rootCmd, getCmd, deleteCmd)
+	}
+	return plugin.NewSpec(rootCmd, plugin.DiscoveryOptions{})
+}
+
+func TestDiffSpecs(t *testing.T) {
+	oldSpec := newDiffTestCommand(nil)
+	assert.Empty(t, plugin.DiffSpecs(oldSpec, oldSpec))
+
+	newSpec := newDiffTestCommand(func(rootCmd, getCmd, deleteCmd *cobra.Command) {
+		plugin.SetArgsSpec(getCmd, plugin.ArgsSpec{Min: 1, Max: 1})
+		getCmd.Flags().Lookup("output").DefValue = "yaml"
+		limit := getCmd.Flags().Lookup("limit")
+		limit.Value = &stringValue{}
+		limit.Shorthand = "n"
+		getCmd.Flags().String("selector", "", "selector")
+		_ = getCmd.MarkFlagRequired("selector")
+		deleteCmd.Use = "remove"
+		deleteCmd.Aliases = []string{"delete"}
+		deleteCmd.Flags().Lookup("force").Deprecated = "it's always forced"
+		rootCmd.AddCommand(&cobra.Command{Use: "create", Run: func(*cobra.Command, []string) {}})
+	})
+
+	assert.Equal(t, plugin.Changes{
+		{Command: "delete", Description: `command was renamed to "remove", the old name is an alias`},
+		{Command: "delete", Breaking: true, Description: `alias "rm" was removed`},
+		{Command: "delete", Flag: "force", Description: "flag was deprecated: it's always forced"},
+		{Command: "get", Breaking: true, Description: "requires at least 1 arguments instead of 0"},
+		{Command: "get", Flag: "limit", Breaking: true, Description: "type changed from int to string"},
+		{Command: "get", Flag: "output", Description: `default changed from "table" to "yaml"`},
+		{Command: "get", Flag: "selector", Breaking: true, Description: "required flag was added"},
+		{Command: "get", Breaking: true, Description: "shorthand -n is used by flags namespace, limit"},
+		{Command: "create", Description: "command was added"},
+	}, plugin.DiffSpecs(oldSpec, newSpec))
+
+	removedSpec := newDiffTestCommand(func(rootCmd, getCmd, deleteCmd *cobra.Command) {
+		rootCmd.RemoveCommand(deleteCmd)
+		rootCmd.PersistentFlags().Lookup("namespace").Shorthand = ""
+		getCmd.Flags().Lookup("output").Shorthand = "f"
+	})
+	assert.Equal(t, []string{
+		"root command --namespace: shorthand -n was removed",
+		"delete: command was removed",
+		"get --output: shorthand changed from -o to -f",
+	}, changeStrings(plugin.DiffSpecs(oldSpec, removedSpec).Breaking()))
+
+	// collisions that already exist are not reported again
+	assert.Empty(t, plugin.DiffSpecs(newSpec, newSpec))
+}
+
+func TestSpecDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec := func(name string, spec plugin.Spec) string {
+		data, err := json.Marshal(spec)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+	oldPath := writeSpec("old.json", newDiffTestCommand(nil))
+	compatiblePath := writeSpec("compatible.json", newDiffTestCommand(func(_, getCmd, _ *cobra.Command) {
+		getCmd.Flags().Bool("watch", false, "watch")
+	}))
+	breakingPath := writeSpec("breaking.json", newDiffTestCommand(func(rootCmd, _, deleteCmd *cobra.Command) {
+		rootCmd.RemoveCommand(deleteCmd)
+	}))
+
+	run := func(args ...string) (string, error) {
+		out := bytes.Buffer{}
+		cmd := plugin.NewSpecDiffCommand(&out)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run(oldPath, compatiblePath)
+	require.NoError(t, err)
+	assert.Equal(t, "non-breaking get --watch: flag was added\n", out)
+
+	out, err = run(oldPath, breakingPath)
+	require.EqualError(t, err, "found 1 breaking changes")
+	assert.Equal(t, "BREAKING     delete: command was removed\n", out)
+}
+
+func changeStrings(changes plugin.Changes) []string {
+	result := []string{}
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	return result
+}
+
+// stringValue is a pflag.Value of type string.
+type stringValue struct {
+	value string
+}
+
+func (v *stringValue) Set(value string) error {
+	v.value = value
+	return nil
+}
+
+func (v *stringValue) Type() string {
+	return "string"
+}
+
+func (v *stringValue) String() string {
+	return v.value
+}
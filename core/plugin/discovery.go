@@ -8,25 +8,63 @@ import (
 	"io"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
 )
 
 const DiscoveryCommandName = "_plugin_commands"
 
 // Spec describes a CLI plugin in a serializable format.
 type Spec struct {
+	// ProtocolVersion is the version of the plugin protocol implemented by the plugin, see ProtocolVersion.
+	ProtocolVersion int `json:"protocol_version,omitempty"`
+	// Name is the name of the plugin's root command.
+	Name string `json:"name,omitempty"`
+	// Version is the plugin's version.
+	Version string `json:"version,omitempty"`
+	// MinHostVersion is the oldest host version the plugin works with. Any host version if empty.
+	MinHostVersion string `json:"min_host_version,omitempty"`
+	// Capabilities lists the optional features supported by the plugin.
+	Capabilities Capabilities `json:"capabilities,omitempty"`
+
 	Commands CommandSpec `json:"commands"`
 }
 
+// DiscoveryOptions contain information about the plugin that cannot be derived from its commands.
+type DiscoveryOptions struct {
+	// MinHostVersion is the oldest host version the plugin works with, e.g. "v2.4.0". Any host version if empty.
+	MinHostVersion string
+	// Capabilities lists the optional features supported by the plugin.
+	Capabilities Capabilities
+}
+
+// NewSpec creates a Spec describing the passed command hierarchy and this binary.
+func NewSpec(rootCmd *cobra.Command, opts DiscoveryOptions) Spec {
+	return Spec{
+		ProtocolVersion: ProtocolVersion,
+		Name:            rootCmd.Name(),
+		Version:         version.GetVersion().GitVersion,
+		MinHostVersion:  opts.MinHostVersion,
+		Capabilities:    opts.Capabilities,
+		Commands:        SpecFromCommand(rootCmd),
+	}
+}
+
 // NewDiscoveryCommand creates a hidden command returning a description of the passed command hierarchy as JSON.
 func NewDiscoveryCommand(output io.Writer, rootCmd *cobra.Command) *cobra.Command {
+	return NewDiscoveryCommandWithOptions(output, rootCmd, &DiscoveryOptions{})
+}
+
+// NewDiscoveryCommandWithOptions creates a hidden command returning a description of the passed command hierarchy as
+// JSON, including the given options. The options are read when the command is run, so they can be changed after
+// creating the command.
+func NewDiscoveryCommandWithOptions(output io.Writer, rootCmd *cobra.Command, opts *DiscoveryOptions) *cobra.Command {
 	discoveryCmd := &cobra.Command{
 		Use:    DiscoveryCommandName,
 		Args:   cobra.NoArgs,
 		Hidden: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			spec := Spec{
-				Commands: SpecFromCommand(rootCmd),
-			}
+			spec := NewSpec(rootCmd, *opts)
 			encoder := json.NewEncoder(output)
 			return encoder.Encode(spec)
 		},
@@ -39,6 +39,9 @@ func TestDiscoveryCommand(t *testing.T) {
 	require.NoError(err)
 
 	assert.JSONEq(`{
+		"protocol_version": 1,
+		"name": "example-plugin",
+		"version": "v0.0.0-dev",
 		"commands": {
 			"use": "example-plugin",
 			"local_flags": [
@@ -3,7 +3,17 @@
 
 package plugin
 
-import "github.com/spf13/pflag"
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FlagValuesAnnotation is the flag annotation containing the values a flag accepts, see MarkFlagValues.
+const FlagValuesAnnotation = "dkp_cli_flag_values"
 
 // FlagSpec is a serializable object that has all the information that describes a pflag.Flag.
 type FlagSpec struct {
@@ -17,6 +27,130 @@ type FlagSpec struct {
 	Hidden              bool                `json:"hidden,omitempty"`
 	ShorthandDeprecated string              `json:"shorthand_deprecated,omitempty"`
 	Annotations         map[string][]string `json:"annotations,omitempty"`
+	// Required is true if the flag must be set (see cobra.Command.MarkFlagRequired).
+	Required bool `json:"required,omitempty"`
+	// Completion contains static hints for completing the flag's value, so hosts don't have to ask the plugin.
+	Completion *FlagCompletion `json:"completion,omitempty"`
+}
+
+// FlagCompletion describes how the value of a flag is completed.
+type FlagCompletion struct {
+	// Values are the values the flag accepts (see MarkFlagValues).
+	Values []string `json:"values,omitempty"`
+	// FileExtensions are the extensions of files the flag accepts (see cobra.Command.MarkFlagFilename).
+	FileExtensions []string `json:"file_extensions,omitempty"`
+	// Directories is true if the flag accepts directories (see cobra.Command.MarkFlagDirname).
+	Directories bool `json:"directories,omitempty"`
+}
+
+// MarkFlagValues marks a flag of cmd to accept only the given values. Other values are rejected when the flag is set,
+// both by the plugin and by hosts the plugin is mounted into, and the values are completed. Each element of the values
+// of slice flags (e.g. "--output=json,yaml") must be one of the values.
+func MarkFlagValues(cmd *cobra.Command, name string, values ...string) error {
+	if err := cmd.Flags().SetAnnotation(name, FlagValuesAnnotation, values); err != nil {
+		return err
+	}
+	flag := cmd.Flags().Lookup(name)
+	if restricted, ok := flag.Value.(*restrictedFlagValue); ok {
+		restricted.values = values
+	} else {
+		flag.Value = &restrictedFlagValue{Value: flag.Value, values: values}
+	}
+	return cmd.RegisterFlagCompletionFunc(name, completeValues(values))
+}
+
+// restrictedFlagValue is a pflag.Value accepting only the given values, see MarkFlagValues.
+type restrictedFlagValue struct {
+	pflag.Value
+	values []string
+}
+
+func (v *restrictedFlagValue) Set(val string) error {
+	if err := checkFlagValue(v.Type(), v.values, val); err != nil {
+		return err
+	}
+	return v.Value.Set(val)
+}
+
+// checkFlagValue returns an error if val is not one of the values a flag of the given type accepts. Values may have
+// a description for completion, separated by a tab.
+func checkFlagValue(typeName string, values []string, val string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	allowed := make([]string, 0, len(values))
+	for _, value := range values {
+		allowed = append(allowed, strings.SplitN(value, "\t", 2)[0]) //nolint:gomnd // Value and description.
+	}
+	elements := []string{val}
+	if strings.HasSuffix(typeName, "Slice") {
+		elements = strings.Split(val, ",")
+	}
+	for _, element := range elements {
+		if !containsString(allowed, element) {
+			return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func completeValues(values []string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completionFromAnnotations derives the completion hints from the annotations set by MarkFlagValues and cobra.
+func completionFromAnnotations(annotations map[string][]string) *FlagCompletion {
+	completion := FlagCompletion{
+		Values:         annotations[FlagValuesAnnotation],
+		FileExtensions: annotations[cobra.BashCompFilenameExt],
+	}
+	if _, ok := annotations[cobra.BashCompSubdirsInDir]; ok {
+		completion.Directories = true
+	}
+	if len(completion.Values) == 0 && len(completion.FileExtensions) == 0 && !completion.Directories {
+		return nil
+	}
+	return &completion
+}
+
+// required returns true if the flag must be set, also for specs created before Required was added.
+func (spec FlagSpec) required() bool {
+	return spec.Required || containsString(spec.Annotations[cobra.BashCompOneRequiredFlag], "true")
+}
+
+// annotations returns the flag annotations including those derived from the completion hints and Required, which
+// cobra uses to complete file names and directories and to validate required flags.
+func (spec FlagSpec) annotations() map[string][]string {
+	if spec.Completion == nil && !spec.Required {
+		return spec.Annotations
+	}
+	annotations := map[string][]string{}
+	for key, value := range spec.Annotations {
+		annotations[key] = value
+	}
+	setDefault := func(key string, value []string) {
+		if _, ok := annotations[key]; !ok {
+			annotations[key] = value
+		}
+	}
+	if spec.Required {
+		setDefault(cobra.BashCompOneRequiredFlag, []string{"true"})
+	}
+	if spec.Completion == nil {
+		return annotations
+	}
+	if len(spec.Completion.Values) > 0 {
+		setDefault(FlagValuesAnnotation, spec.Completion.Values)
+	}
+	if len(spec.Completion.FileExtensions) > 0 {
+		setDefault(cobra.BashCompFilenameExt, spec.Completion.FileExtensions)
+	}
+	if spec.Completion.Directories {
+		setDefault(cobra.BashCompSubdirsInDir, []string{})
+	}
+	return annotations
 }
 
 // SpecFromFlag creates a FlagSpec describing a flag.
@@ -32,6 +166,8 @@ func SpecFromFlag(flag *pflag.Flag) FlagSpec {
 		Hidden:              flag.Hidden,
 		ShorthandDeprecated: flag.ShorthandDeprecated,
 		Annotations:         flag.Annotations,
+		Required:            containsString(flag.Annotations[cobra.BashCompOneRequiredFlag], "true"),
+		Completion:          completionFromAnnotations(flag.Annotations),
 	}
 }
 
@@ -44,8 +180,21 @@ func SpecsFromFlagset(flags *pflag.FlagSet) []FlagSpec {
 	return flagSpecs
 }
 
-// ToFlag creates a pflag.Flag based on this FlagSpec.
+// ToFlag creates a pflag.Flag based on this FlagSpec. Values of the standard pflag types are validated when set, so
+// invalid values are reported like in the plugin. Values of other types are accepted as they are.
 func (spec FlagSpec) ToFlag() *pflag.Flag {
+	noOptDefaultValue := spec.NoOptDefaultValue
+	if noOptDefaultValue == "" {
+		// set by pflag for these types, so "--flag" works without a value
+		switch spec.Type {
+		case "bool":
+			noOptDefaultValue = "true"
+		case "count":
+			noOptDefaultValue = "+1"
+		}
+	}
+
+	annotations := spec.annotations()
 	return &pflag.Flag{
 		Name:      spec.Name,
 		Shorthand: spec.Shorthand,
@@ -53,25 +202,96 @@ func (spec FlagSpec) ToFlag() *pflag.Flag {
 		Value: &typedFlagValue{
 			typeName: spec.Type,
 			value:    spec.DefaultValue,
+			allowed:  annotations[FlagValuesAnnotation],
 		},
 		DefValue:            spec.DefaultValue,
-		NoOptDefVal:         spec.NoOptDefaultValue,
+		NoOptDefVal:         noOptDefaultValue,
 		Deprecated:          spec.Deprecated,
 		Hidden:              spec.Hidden,
 		ShorthandDeprecated: spec.ShorthandDeprecated,
-		Annotations:         spec.Annotations,
+		Annotations:         annotations,
+	}
+}
+
+// standardFlagTypes define a flag of each standard pflag type named "value", keyed by type name.
+var standardFlagTypes = map[string]func(flags *pflag.FlagSet){
+	"bool":           func(flags *pflag.FlagSet) { flags.Bool("value", false, "") },
+	"boolSlice":      func(flags *pflag.FlagSet) { flags.BoolSlice("value", nil, "") },
+	"bytesBase64":    func(flags *pflag.FlagSet) { flags.BytesBase64("value", nil, "") },
+	"bytesHex":       func(flags *pflag.FlagSet) { flags.BytesHex("value", nil, "") },
+	"count":          func(flags *pflag.FlagSet) { flags.Count("value", "") },
+	"duration":       func(flags *pflag.FlagSet) { flags.Duration("value", 0, "") },
+	"durationSlice":  func(flags *pflag.FlagSet) { flags.DurationSlice("value", nil, "") },
+	"float32":        func(flags *pflag.FlagSet) { flags.Float32("value", 0, "") },
+	"float32Slice":   func(flags *pflag.FlagSet) { flags.Float32Slice("value", nil, "") },
+	"float64":        func(flags *pflag.FlagSet) { flags.Float64("value", 0, "") },
+	"float64Slice":   func(flags *pflag.FlagSet) { flags.Float64Slice("value", nil, "") },
+	"int":            func(flags *pflag.FlagSet) { flags.Int("value", 0, "") },
+	"int8":           func(flags *pflag.FlagSet) { flags.Int8("value", 0, "") },
+	"int16":          func(flags *pflag.FlagSet) { flags.Int16("value", 0, "") },
+	"int32":          func(flags *pflag.FlagSet) { flags.Int32("value", 0, "") },
+	"int64":          func(flags *pflag.FlagSet) { flags.Int64("value", 0, "") },
+	"intSlice":       func(flags *pflag.FlagSet) { flags.IntSlice("value", nil, "") },
+	"int32Slice":     func(flags *pflag.FlagSet) { flags.Int32Slice("value", nil, "") },
+	"int64Slice":     func(flags *pflag.FlagSet) { flags.Int64Slice("value", nil, "") },
+	"ip":             func(flags *pflag.FlagSet) { flags.IP("value", nil, "") },
+	"ipMask":         func(flags *pflag.FlagSet) { flags.IPMask("value", nil, "") },
+	"ipNet":          func(flags *pflag.FlagSet) { flags.IPNet("value", net.IPNet{}, "") },
+	"ipSlice":        func(flags *pflag.FlagSet) { flags.IPSlice("value", nil, "") },
+	"string":         func(flags *pflag.FlagSet) { flags.String("value", "", "") },
+	"stringArray":    func(flags *pflag.FlagSet) { flags.StringArray("value", nil, "") },
+	"stringSlice":    func(flags *pflag.FlagSet) { flags.StringSlice("value", nil, "") },
+	"stringToInt":    func(flags *pflag.FlagSet) { flags.StringToInt("value", nil, "") },
+	"stringToInt64":  func(flags *pflag.FlagSet) { flags.StringToInt64("value", nil, "") },
+	"stringToString": func(flags *pflag.FlagSet) { flags.StringToString("value", nil, "") },
+	"uint":           func(flags *pflag.FlagSet) { flags.Uint("value", 0, "") },
+	"uint8":          func(flags *pflag.FlagSet) { flags.Uint8("value", 0, "") },
+	"uint16":         func(flags *pflag.FlagSet) { flags.Uint16("value", 0, "") },
+	"uint32":         func(flags *pflag.FlagSet) { flags.Uint32("value", 0, "") },
+	"uint64":         func(flags *pflag.FlagSet) { flags.Uint64("value", 0, "") },
+	"uintSlice":      func(flags *pflag.FlagSet) { flags.UintSlice("value", nil, "") },
+}
+
+// newStandardFlagValue returns a new value of the standard pflag type, nil for other types.
+func newStandardFlagValue(typeName string) pflag.Value {
+	define, ok := standardFlagTypes[typeName]
+	if !ok {
+		return nil
 	}
+	flags := pflag.NewFlagSet(typeName, pflag.ContinueOnError)
+	define(flags)
+	return flags.Lookup("value").Value
 }
 
-// typedFlagValue is a dummy pflag.Value that knows its type. That's all we need here.
-// All standard pflag.Value implementations are private.
+// typedFlagValue is a pflag.Value that knows its type. Values of standard pflag types are parsed by the respective
+// pflag.Value, which are all private.
 type typedFlagValue struct {
 	typeName string
-	value    string
+	// value is the default value until the value is set.
+	value string
+	// parsed is the parsed value once a value of a standard type is set.
+	parsed pflag.Value
+	// rawValues are all values set, so they can be passed on to a plugin as they were given.
+	rawValues []string
+	// allowed are the values the flag accepts, see MarkFlagValues. Any value is accepted if empty.
+	allowed []string
 }
 
 func (s *typedFlagValue) Set(val string) error {
-	s.value = val
+	if err := checkFlagValue(s.typeName, s.allowed, val); err != nil {
+		return err
+	}
+	if s.parsed == nil {
+		s.parsed = newStandardFlagValue(s.typeName)
+	}
+	if s.parsed != nil {
+		if err := s.parsed.Set(val); err != nil {
+			return err
+		}
+	} else {
+		s.value = val
+	}
+	s.rawValues = append(s.rawValues, val)
 	return nil
 }
 
@@ -80,5 +300,8 @@ func (s typedFlagValue) Type() string {
 }
 
 func (s typedFlagValue) String() string {
+	if s.parsed != nil {
+		return s.parsed.String()
+	}
 	return s.value
 }
@@ -6,8 +6,10 @@ package plugin_test
 import (
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
 )
@@ -21,6 +23,8 @@ func TestFlagsToSpecAndBack(t *testing.T) {
 	flags.Bool("boolean", true, "how to use")
 	flags.String("annotated", "", "how to use")
 	flags.SetAnnotation("annotated", "key", []string{"value1", "value2"})
+	flags.String("filename", "", "how to use")
+	flags.SetAnnotation("filename", cobra.BashCompFilenameExt, []string{"yaml", "yml"})
 	flags.String("hidden", "", "how to use")
 	flags.MarkHidden("hidden")
 	flags.String("deprecated", "", "how to use")
@@ -44,3 +48,71 @@ func assertFlagsEqual(t *testing.T, expected, actual *pflag.Flag) {
 	actual.Value = expected.Value
 	assert.Equal(expected, actual)
 }
+
+func TestFlagCompletionToFlag(t *testing.T) {
+	spec := plugin.FlagSpec{
+		Type:       "string",
+		Name:       "dir",
+		Completion: &plugin.FlagCompletion{Values: []string{"a", "b"}, Directories: true},
+	}
+	assert.Equal(t, map[string][]string{
+		plugin.FlagValuesAnnotation: {"a", "b"},
+		cobra.BashCompSubdirsInDir:  {},
+	}, spec.ToFlag().Annotations)
+}
+
+func TestFlagValueValidation(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	for _, spec := range []plugin.FlagSpec{
+		{Type: "int", Name: "replicas", DefaultValue: "1"},
+		{Type: "bool", Name: "force", DefaultValue: "false"},
+		{Type: "count", Name: "verbose", Shorthand: "v", DefaultValue: "0"},
+		{Type: "duration", Name: "timeout", DefaultValue: "0s"},
+		{Type: "stringToString", Name: "labels", DefaultValue: "[]"},
+		{Type: "ipNet", Name: "cidr"},
+		{Type: "custom", Name: "custom", DefaultValue: "default"},
+	} {
+		flags.AddFlag(spec.ToFlag())
+	}
+
+	assert.EqualError(t, flags.Parse([]string{"--replicas=abc"}),
+		`invalid argument "abc" for "--replicas" flag: strconv.ParseInt: parsing "abc": invalid syntax`)
+	assert.Error(t, flags.Parse([]string{"--timeout=forever"}))
+	assert.Error(t, flags.Parse([]string{"--labels=a"}))
+	assert.Error(t, flags.Parse([]string{"--cidr=10.0.0.1"}))
+
+	assert.Equal(t, "default", flags.Lookup("custom").Value.String())
+	assert.NoError(t, flags.Parse([]string{
+		"--replicas=3", "--force", "-vv", "--labels=a=b", "--cidr=10.0.0.0/8", "--custom=anything",
+	}))
+	assert.Equal(t, "3", flags.Lookup("replicas").Value.String())
+	assert.Equal(t, "true", flags.Lookup("force").Value.String())
+	assert.Equal(t, "2", flags.Lookup("verbose").Value.String())
+	assert.Equal(t, "[a=b]", flags.Lookup("labels").Value.String())
+	assert.Equal(t, "10.0.0.0/8", flags.Lookup("cidr").Value.String())
+	assert.Equal(t, "anything", flags.Lookup("custom").Value.String())
+}
+
+func TestMarkFlagValues(t *testing.T) {
+	cmd := &cobra.Command{Use: "cmd", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().String("format", "json", "format")
+	cmd.Flags().StringSlice("columns", nil, "columns")
+	require.NoError(t, plugin.MarkFlagValues(cmd, "format", "json\tJSON output", "yaml"))
+	require.NoError(t, plugin.MarkFlagValues(cmd, "columns", "name", "age"))
+	assert.Error(t, plugin.MarkFlagValues(cmd, "unknown", "a"))
+
+	flags := cmd.Flags()
+	assert.EqualError(t, flags.Parse([]string{"--format=xml"}),
+		`invalid argument "xml" for "--format" flag: must be one of json, yaml`)
+	assert.Error(t, flags.Parse([]string{"--columns=name,size"}))
+	require.NoError(t, flags.Parse([]string{"--format=yaml", "--columns=name,age"}))
+	assert.Equal(t, "yaml", flags.Lookup("format").Value.String())
+	assert.Equal(t, "stringSlice", flags.Lookup("columns").Value.Type())
+
+	// hosts reject the values, too
+	hostFlags := pflag.NewFlagSet("host", pflag.ContinueOnError)
+	hostFlags.AddFlag(plugin.SpecFromFlag(flags.Lookup("format")).ToFlag())
+	assert.EqualError(t, hostFlags.Parse([]string{"--format=xml"}),
+		`invalid argument "xml" for "--format" flag: must be one of json, yaml`)
+	assert.NoError(t, hostFlags.Parse([]string{"--format=json"}))
+}
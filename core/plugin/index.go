@@ -0,0 +1,222 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Index lists plugins available for installation (see Installer). It is stored as YAML, e.g.:
+//
+//	plugins:
+//	- name: example
+//	  version: v1.2.0
+//	  description: Manage examples
+//	  platforms:
+//	  - os: linux
+//	    arch: amd64
+//	    url: https://example.com/dkp-example_v1.2.0_linux_amd64
+//	    sha256: 3b0b5f8f...
+//
+// URLs may be relative to the location of the index.
+type Index struct {
+	Plugins []IndexEntry `json:"plugins"`
+}
+
+// IndexEntry is a version of a plugin.
+type IndexEntry struct {
+	// Name is the plugin's name without prefix, e.g. "example" for "dkp-example".
+	Name string `json:"name"`
+	// Version is the plugin's semantic version, e.g. "v1.2.0".
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Platforms contains the executables for the supported platforms.
+	Platforms []IndexPlatform `json:"platforms"`
+}
+
+// IndexPlatform is the executable of a plugin version for a platform.
+type IndexPlatform struct {
+	// OS and Arch are the platform like GOOS and GOARCH, e.g. "linux" and "amd64".
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// URL is the location of the executable (not an archive), an HTTP(S) URL or a file.
+	URL string `json:"url"`
+	// SHA256 is the hex-encoded SHA-256 digest of the executable.
+	SHA256 string `json:"sha256"`
+}
+
+// LoadIndex reads the index from location, an HTTP(S) URL or a file. Relative URLs of executables are resolved
+// against location.
+func LoadIndex(ctx context.Context, client *http.Client, location string) (*Index, error) {
+	data, err := readLocation(ctx, client, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin index: %w", err)
+	}
+	index := &Index{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid plugin index %s: %w", location, err)
+	}
+
+	for i := range index.Plugins {
+		entry := &index.Plugins[i]
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("invalid plugin index %s: %w", location, err)
+		}
+		for j := range entry.Platforms {
+			entry.Platforms[j].URL = resolveLocation(location, entry.Platforms[j].URL)
+		}
+	}
+	return index, nil
+}
+
+// pluginNamePattern matches valid plugin names, which are used in file names.
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ValidatePluginName returns an error if name is not a valid plugin name. Only lowercase letters, digits, "_" and "-"
+// are allowed, so a name cannot refer to a file outside of the plugin directory.
+func ValidatePluginName(name string) error {
+	if !pluginNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid plugin name %q: only lowercase letters, digits, \"_\" and \"-\" are allowed", name)
+	}
+	return nil
+}
+
+func (e IndexEntry) validate() error {
+	if err := ValidatePluginName(e.Name); err != nil {
+		return err
+	}
+	if _, err := parseVersion(e.Version); err != nil {
+		return fmt.Errorf("plugin %s: %w", e.Name, err)
+	}
+	for _, platform := range e.Platforms {
+		if platform.URL == "" {
+			return fmt.Errorf("plugin %s %s: URL for %s/%s must not be empty", e.Name, e.Version, platform.OS, platform.Arch)
+		}
+		if digest, err := hex.DecodeString(platform.SHA256); err != nil || len(digest) != 32 { //nolint:gomnd // SHA-256.
+			return fmt.Errorf("plugin %s %s: invalid SHA-256 digest %q for %s/%s",
+				e.Name, e.Version, platform.SHA256, platform.OS, platform.Arch)
+		}
+	}
+	return nil
+}
+
+// Latest returns the newest version of the plugin.
+func (i *Index) Latest(name string) (IndexEntry, bool) {
+	var latest IndexEntry
+	found := false
+	for _, entry := range i.Plugins {
+		if entry.Name != name {
+			continue
+		}
+		if !found || compareVersions(entry.Version, latest.Version) > 0 {
+			latest = entry
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// Find returns the given version of the plugin. The "v" prefix of the version is optional.
+func (i *Index) Find(name, version string) (IndexEntry, bool) {
+	for _, entry := range i.Plugins {
+		if entry.Name == name && compareVersions(entry.Version, version) == 0 {
+			return entry, true
+		}
+	}
+	return IndexEntry{}, false
+}
+
+// Platform returns the executable for the given platform.
+func (e IndexEntry) Platform(goos, goarch string) (IndexPlatform, bool) {
+	for _, platform := range e.Platforms {
+		if platform.OS == goos && platform.Arch == goarch {
+			return platform, true
+		}
+	}
+	return IndexPlatform{}, false
+}
+
+// compareVersions compares two semantic versions, see semanticVersion.compare. Invalid versions are equal if they are
+// identical and older than valid versions otherwise.
+func compareVersions(a, b string) int {
+	versionA, errA := parseVersion(a)
+	versionB, errB := parseVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return versionA.compare(versionB)
+	case a == b:
+		return 0
+	case errA != nil:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func isHTTPLocation(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveLocation resolves a location relative to base, both HTTP(S) URLs or files.
+func resolveLocation(base, location string) string {
+	if isHTTPLocation(location) || filepath.IsAbs(location) || strings.HasPrefix(location, "file://") {
+		return location
+	}
+	if isHTTPLocation(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return location
+		}
+		ref, err := url.Parse(location)
+		if err != nil {
+			return location
+		}
+		return baseURL.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(strings.TrimPrefix(base, "file://")), filepath.FromSlash(location))
+}
+
+// openLocation opens an HTTP(S) URL or a file.
+func openLocation(ctx context.Context, client *http.Client, location string) (io.ReadCloser, error) {
+	if !isHTTPLocation(location) {
+		return os.Open(strings.TrimPrefix(location, "file://"))
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func readLocation(ctx context.Context, client *http.Client, location string) ([]byte, error) {
+	r, err := openLocation(ctx, client, location)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
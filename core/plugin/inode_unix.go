@@ -0,0 +1,19 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package plugin
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode of the file, 0 if it is unknown.
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino) //nolint:unconvert // Type differs between platforms.
+	}
+	return 0
+}
@@ -0,0 +1,11 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import "os"
+
+// fileInode returns 0, files don't have inodes on Windows.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
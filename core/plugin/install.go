@@ -0,0 +1,284 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
+)
+
+// receiptsDir is the directory within Installer.Dir containing a receipt for each installed plugin.
+const receiptsDir = ".installed"
+
+// Installer installs plugins from an Index into a managed directory, which should be one of the directories the
+// Manager finds plugins in.
+//
+// Executables are verified with their SHA-256 digest and checked for compatibility with the host (see
+// Spec.CheckCompatibility) before they replace the installed version atomically, so a failed installation or upgrade
+// never leaves a broken plugin behind.
+type Installer struct {
+	// Prefix of plugin executables, e.g. "dkp". A trailing "-" is optional.
+	Prefix string
+	// Dir is the directory plugins are installed into.
+	Dir string
+	// Index is the location of the index, an HTTP(S) URL or a file.
+	Index string
+	// HTTPClient is used to download the index and executables. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// HostVersion is the version of the host, used to check the compatibility of plugins. Defaults to the version of
+	// this binary.
+	HostVersion string
+	// DiscoveryTimeout limits the time a plugin has to describe its commands. DefaultDiscoveryTimeout if 0.
+	DiscoveryTimeout time.Duration
+}
+
+// InstalledPlugin describes a plugin installed by an Installer.
+type InstalledPlugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Path is the path of the executable.
+	Path string `json:"path"`
+	// URL is the location the executable was downloaded from.
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	// Warning is set if the plugin may not work as expected with the host.
+	Warning *CompatibilityWarning `json:"-"`
+}
+
+// NewInstaller creates an Installer for plugins with the given prefix, installing them from the index into dir.
+func NewInstaller(prefix, dir, index string) *Installer {
+	return &Installer{
+		Prefix: prefix,
+		Dir:    dir,
+		Index:  index,
+	}
+}
+
+// LoadIndex loads the index.
+func (i *Installer) LoadIndex(ctx context.Context) (*Index, error) {
+	if i.Index == "" {
+		return nil, errors.New("no plugin index configured")
+	}
+	return LoadIndex(ctx, i.HTTPClient, i.Index)
+}
+
+// Installed returns the installed plugins, sorted by name.
+func (i *Installer) Installed() ([]InstalledPlugin, error) {
+	entries, err := os.ReadDir(filepath.Join(i.Dir, receiptsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return []InstalledPlugin{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	installed := []InstalledPlugin{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if name == entry.Name() {
+			continue
+		}
+		p, err := i.installed(name)
+		if err != nil {
+			return nil, err
+		}
+		installed = append(installed, p)
+	}
+	sort.Slice(installed, func(a, b int) bool { return installed[a].Name < installed[b].Name })
+	return installed, nil
+}
+
+// installed returns the installed plugin with the given name, an error wrapping os.ErrNotExist if it is not installed.
+func (i *Installer) installed(name string) (InstalledPlugin, error) {
+	if err := ValidatePluginName(name); err != nil {
+		return InstalledPlugin{}, err
+	}
+	data, err := os.ReadFile(i.receiptPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return InstalledPlugin{}, fmt.Errorf("plugin %s is not installed: %w", name, err)
+	}
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	p := InstalledPlugin{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return InstalledPlugin{}, fmt.Errorf("invalid receipt of plugin %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Install installs the given version of the plugin, the latest version if version is empty. An installed version of
+// the plugin is replaced.
+func (i *Installer) Install(ctx context.Context, name, version string) (InstalledPlugin, error) {
+	if err := ValidatePluginName(name); err != nil {
+		return InstalledPlugin{}, err
+	}
+	index, err := i.LoadIndex(ctx)
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	entry, ok := index.Latest(name)
+	if version != "" {
+		entry, ok = index.Find(name, version)
+	}
+	if !ok {
+		if version != "" {
+			return InstalledPlugin{}, fmt.Errorf("plugin %s %s not found in index", name, version)
+		}
+		return InstalledPlugin{}, fmt.Errorf("plugin %s not found in index", name)
+	}
+	return i.install(ctx, entry)
+}
+
+// Upgrade installs the latest version of the installed plugin, if it is newer than the installed version. It returns
+// the installed plugin and whether it was upgraded.
+func (i *Installer) Upgrade(ctx context.Context, name string) (InstalledPlugin, bool, error) {
+	if _, err := i.installed(name); err != nil {
+		return InstalledPlugin{}, false, err
+	}
+	index, err := i.LoadIndex(ctx)
+	if err != nil {
+		return InstalledPlugin{}, false, err
+	}
+	return i.UpgradeFromIndex(ctx, index, name)
+}
+
+// UpgradeFromIndex is like Upgrade, but uses the given index instead of loading it, e.g. to upgrade multiple plugins.
+func (i *Installer) UpgradeFromIndex(ctx context.Context, index *Index, name string) (InstalledPlugin, bool, error) {
+	current, err := i.installed(name)
+	if err != nil {
+		return InstalledPlugin{}, false, err
+	}
+	latest, ok := index.Latest(name)
+	if !ok || compareVersions(latest.Version, current.Version) <= 0 {
+		return current, false, nil
+	}
+	upgraded, err := i.install(ctx, latest)
+	return upgraded, err == nil, err
+}
+
+// Remove removes the installed plugin.
+func (i *Installer) Remove(name string) error {
+	p, err := i.installed(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Remove(i.receiptPath(name))
+}
+
+func (i *Installer) install(ctx context.Context, entry IndexEntry) (InstalledPlugin, error) {
+	platform, ok := entry.Platform(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return InstalledPlugin{}, fmt.Errorf("plugin %s %s is not available for %s/%s",
+			entry.Name, entry.Version, runtime.GOOS, runtime.GOARCH)
+	}
+
+	if err := os.MkdirAll(filepath.Join(i.Dir, receiptsDir), 0o755); err != nil { //nolint:gosec // Executables.
+		return InstalledPlugin{}, err
+	}
+	// download into the same directory, so the installed version can be replaced atomically
+	tmpFile, err := os.CreateTemp(i.Dir, ".download-*"+executableSuffix())
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	defer os.Remove(tmpFile.Name())
+	err = download(ctx, i.HTTPClient, platform, tmpFile)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return InstalledPlugin{}, fmt.Errorf("failed to download plugin %s %s: %w", entry.Name, entry.Version, err)
+	}
+	if err := os.Chmod(tmpFile.Name(), 0o755); err != nil { //nolint:gosec // Executables must be executable.
+		return InstalledPlugin{}, err
+	}
+
+	// check the new version before it replaces the installed one
+	manager := &Manager{DiscoveryTimeout: i.DiscoveryTimeout}
+	spec, err := manager.discoverSpec(ctx, tmpFile.Name())
+	if err != nil {
+		return InstalledPlugin{}, &DiscoveryError{Path: platform.URL, Err: err}
+	}
+	hostVersion := i.HostVersion
+	if hostVersion == "" {
+		hostVersion = version.GetVersion().GitVersion
+	}
+	installed := InstalledPlugin{
+		Name:    entry.Name,
+		Version: entry.Version,
+		Path:    filepath.Join(i.Dir, strings.TrimSuffix(i.Prefix, "-")+"-"+entry.Name+executableSuffix()),
+		URL:     platform.URL,
+		SHA256:  strings.ToLower(platform.SHA256),
+	}
+	if err := spec.CheckCompatibility(platform.URL, hostVersion); err != nil {
+		if !errors.As(err, &installed.Warning) {
+			return InstalledPlugin{}, err
+		}
+	}
+
+	if err := os.Rename(tmpFile.Name(), installed.Path); err != nil {
+		return InstalledPlugin{}, err
+	}
+	data, err := json.Marshal(installed)
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	return installed, writeFileAtomically(i.receiptPath(entry.Name), data)
+}
+
+func (i *Installer) receiptPath(name string) string {
+	return filepath.Join(i.Dir, receiptsDir, name+".json")
+}
+
+// download writes the executable to w, verifying its digest.
+func download(ctx context.Context, client *http.Client, platform IndexPlatform, w io.Writer) error {
+	r, err := openLocation(ctx, client, platform.URL)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), r); err != nil {
+		return err
+	}
+	if digest := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(digest, platform.SHA256) {
+		return fmt.Errorf("SHA-256 digest of %s is %s, expected %s", platform.URL, digest, platform.SHA256)
+	}
+	return nil
+}
+
+// writeFileAtomically writes to a temporary file first, so readers never see a partially written file.
+func writeFileAtomically(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// scriptPlugin returns a plugin executable printing a spec with the given version and minimum host version.
+func scriptPlugin(version, minHostVersion string) []byte {
+	return []byte(fmt.Sprintf(`#!/bin/sh
+echo '{"protocol_version": 1, "name": "dkp-example", "version": "%s", "min_host_version": "%s", `+
+		`"commands": {"use": "dkp-example"}}'
+`, version, minHostVersion))
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstaller(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("script plugins are not supported on Windows")
+	}
+
+	files := map[string][]byte{
+		"/v1.0.0": scriptPlugin("v1.0.0", ""),
+		"/v1.1.0": scriptPlugin("v1.1.0", ""),
+		"/v2.0.0": scriptPlugin("v2.0.0", "v99.0.0"),
+	}
+	index := ""
+	platform := func(path, sha256 string) string {
+		return fmt.Sprintf("\n  - os: %s\n    arch: %s\n    url: %s\n    sha256: %s",
+			runtime.GOOS, runtime.GOARCH, path, sha256)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.yaml" {
+			_, _ = w.Write([]byte(index))
+			return
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	installer := plugin.NewInstaller("dkp", dir, server.URL+"/index.yaml")
+	installer.HostVersion = "v2.4.0"
+	ctx := context.Background()
+	path := filepath.Join(dir, "dkp-example")
+
+	index = `plugins:
+- name: example
+  version: v1.0.0
+  platforms:` + platform("v1.0.0", digest(files["/v1.0.0"])) + `
+- name: other
+  version: v1.0.0
+  platforms:` + platform("missing", digest(nil))
+
+	installed, err := installer.Install(ctx, "example", "")
+	require.NoError(t, err)
+	assert.Equal(t, plugin.InstalledPlugin{
+		Name: "example", Version: "v1.0.0", Path: path,
+		URL: server.URL + "/v1.0.0", SHA256: digest(files["/v1.0.0"]),
+	}, installed)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, files["/v1.0.0"], data)
+
+	// the installed plugin is found by a manager
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	plugins, errs := manager.Discover(ctx)
+	require.Empty(t, errs)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "v1.0.0", plugins[0].Spec.Version)
+
+	_, err = installer.Install(ctx, "other", "")
+	assert.ErrorContains(t, err, "failed to download plugin other v1.0.0: GET "+server.URL+"/missing: 404 Not Found")
+	_, err = installer.Install(ctx, "example", "v3.0.0")
+	assert.EqualError(t, err, "plugin example v3.0.0 not found in index")
+
+	upgraded, ok, err := installer.Upgrade(ctx, "example")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, installed, upgraded)
+
+	// a corrupted download is not installed
+	index += `
+- name: example
+  version: v1.1.0
+  platforms:` + platform("v1.1.0", digest(files["/v1.0.0"]))
+	_, _, err = installer.Upgrade(ctx, "example")
+	assert.ErrorContains(t, err, "SHA-256 digest of "+server.URL+"/v1.1.0 is "+digest(files["/v1.1.0"]))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, files["/v1.0.0"], data)
+
+	// an incompatible version is not installed
+	index = `plugins:
+- name: example
+  version: v1.0.0
+  platforms:` + platform("v1.0.0", digest(files["/v1.0.0"])) + `
+- name: example
+  version: v2.0.0
+  platforms:` + platform("v2.0.0", digest(files["/v2.0.0"]))
+	_, _, err = installer.Upgrade(ctx, "example")
+	incompatibleErr := &plugin.IncompatibleError{}
+	require.ErrorAs(t, err, &incompatibleErr)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, files["/v1.0.0"], data)
+
+	index = `plugins:
+- name: example
+  version: v1.1.0
+  platforms:` + platform("v1.1.0", digest(files["/v1.1.0"]))
+	upgraded, ok, err = installer.Upgrade(ctx, "example")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v1.1.0", upgraded.Version)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, files["/v1.1.0"], data)
+
+	all, err := installer.Installed()
+	require.NoError(t, err)
+	assert.Equal(t, []plugin.InstalledPlugin{upgraded}, all)
+
+	require.NoError(t, installer.Remove("example"))
+	assert.NoFileExists(t, path)
+	all, err = installer.Installed()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+	assert.ErrorIs(t, installer.Remove("example"), os.ErrNotExist)
+
+	// names must not refer to files outside of the plugin directory
+	_, err = installer.Install(ctx, "../example", "")
+	assert.EqualError(t, err, `invalid plugin name "../example": only lowercase letters, digits, "_" and "-" are allowed`)
+	assert.ErrorContains(t, installer.Remove("../../etc/x"), "invalid plugin name")
+	index = `plugins:
+- name: ../../../usr/local/bin/x
+  version: v1.0.0
+  platforms:` + platform("v1.0.0", digest(files["/v1.0.0"]))
+	_, err = installer.Install(ctx, "x", "")
+	assert.ErrorContains(t, err, `invalid plugin name "../../../usr/local/bin/x"`)
+}
+
+func TestLoadIndexFromFile(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "index.yaml")
+	require.NoError(t, os.WriteFile(indexPath, []byte(`plugins:
+- name: example
+  version: v1.0.0
+  platforms:
+  - os: linux
+    arch: amd64
+    url: bin/dkp-example
+    sha256: `+digest(nil)+`
+- name: example
+  version: v1.2.0-rc.1
+  platforms: []
+- name: example
+  version: v1.1.0
+  platforms: []
+`), 0o600))
+
+	index, err := plugin.LoadIndex(context.Background(), nil, indexPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "bin", "dkp-example"), index.Plugins[0].Platforms[0].URL)
+	latest, ok := index.Latest("example")
+	require.True(t, ok)
+	assert.Equal(t, "v1.2.0-rc.1", latest.Version)
+	_, ok = index.Find("example", "1.1.0")
+	assert.True(t, ok)
+
+	require.NoError(t, os.WriteFile(indexPath, []byte("plugins:\n- name: example\n  version: latest\n"), 0o600))
+	_, err = plugin.LoadIndex(context.Background(), nil, indexPath)
+	assert.EqualError(t, err, "invalid plugin index "+indexPath+`: plugin example: "latest" is not a semantic version`)
+}
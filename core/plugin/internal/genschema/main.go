@@ -0,0 +1,27 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// genschema writes the JSON Schema of the plugin discovery format to the file given as argument.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func main() {
+	if len(os.Args) != 2 { //nolint:gomnd // Program name and file.
+		fmt.Fprintln(os.Stderr, "usage: genschema FILE")
+		os.Exit(2)
+	}
+	schema, err := plugin.DiscoverySchema()
+	if err == nil {
+		err = os.WriteFile(os.Args[1], schema, 0o644) //nolint:gosec // The schema is public.
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,582 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// DefaultDiscoveryTimeout is the time a plugin has to describe its commands.
+const DefaultDiscoveryTimeout = 10 * time.Second
+
+// builtinCommands are added to every command created with the runtime, the host provides its own.
+var builtinCommands = map[string]bool{
+	"help":       true,
+	"version":    true,
+	"completion": true,
+}
+
+// Plugin is an executable found by a Manager.
+type Plugin struct {
+	// Name is the executable's name without prefix, e.g. "foo" for "dkp-foo".
+	Name string
+	// Path is the absolute path of the executable.
+	Path string
+	// Spec describes the plugin's commands. It is only set once the plugin was discovered.
+	Spec Spec
+}
+
+// DiscoveryError is returned for plugins that could not describe their commands.
+type DiscoveryError struct {
+	Path string
+	Err  error
+}
+
+func (e *DiscoveryError) Error() string {
+	return fmt.Sprintf("failed to discover commands of plugin %s: %v", e.Path, e.Err)
+}
+
+func (e *DiscoveryError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError is returned by plugin commands if the plugin exited with a non-zero exit code. The plugin has already
+// reported the error itself, hosts should usually just exit with the same code:
+//
+//	if err := rootCmd.Execute(); err != nil {
+//		var exitErr *plugin.ExitError
+//		if errors.As(err, &exitErr) {
+//			os.Exit(exitErr.ExitCode())
+//		}
+//		os.Exit(1)
+//	}
+type ExitError struct {
+	Plugin string
+	Code   int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("plugin %q exited with code %d", e.Plugin, e.Code)
+}
+
+// ExitCode returns the exit code of the plugin, e.g. to exit the host with it.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Manager finds plugin executables named "<prefix>-<name>" and mounts their commands into a host's root command.
+type Manager struct {
+	// Prefix of plugin executables, e.g. "dkp". A trailing "-" is optional.
+	Prefix string
+	// Dirs are searched for plugins before the directories in PATH.
+	Dirs []string
+	// DisablePath disables searching the directories in PATH.
+	DisablePath bool
+	// DiscoveryTimeout limits the time a plugin has to describe its commands. DefaultDiscoveryTimeout if 0.
+	DiscoveryTimeout time.Duration
+	// Env contains additional environment variables ("key=value") plugins are executed with.
+	Env []string
+	// Output renders the output of plugins with CapabilityEventStream. If not set, plugins write to stderr directly.
+	Output output.Output
+	// HostVersion is the version of the host, used to check the compatibility of plugins. Defaults to the version of
+	// this binary.
+	HostVersion string
+	// Cache stores discovered specs, so plugins are only executed for discovery if they changed. Optional.
+	Cache *Cache
+	// ConflictPolicy defines how Mount handles commands, aliases and flags of plugins conflicting with those of the
+	// host or other plugins. Defaults to ConflictPolicyFirstWins.
+	ConflictPolicy ConflictPolicy
+	// CacheOnly disables executing plugins for discovery, only plugins in Cache are discovered. This is useful for
+	// shell completion, which must be fast.
+	CacheOnly bool
+}
+
+// NewManager creates a Manager finding plugins with the given prefix in dirs and PATH.
+func NewManager(prefix string, dirs ...string) *Manager {
+	return &Manager{
+		Prefix: prefix,
+		Dirs:   dirs,
+	}
+}
+
+// Find returns all plugin executables, sorted by name. If executables with the same name exist in multiple
+// directories, the first one in Dirs and PATH wins. Symlinks to the host's own executable (e.g. created by the
+// symlinks package for subcommands) are not plugins and skipped.
+func (m *Manager) Find() ([]Plugin, error) {
+	prefix := strings.TrimSuffix(m.Prefix, "-") + "-"
+	host := hostExecutable()
+	dirs := append([]string{}, m.Dirs...)
+	if !m.DisablePath {
+		dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	}
+
+	plugins := []Plugin{}
+	found := map[string]bool{}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), executableSuffix())
+			if !strings.HasPrefix(name, prefix) || name == prefix || found[name] {
+				continue
+			}
+			path, err := filepath.Abs(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if !isExecutable(path) {
+				continue
+			}
+			if target, err := filepath.EvalSymlinks(path); err == nil && host != "" && target == host {
+				continue
+			}
+			found[name] = true
+			plugins = append(plugins, Plugin{Name: strings.TrimPrefix(name, prefix), Path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// Discover finds all plugins and invokes their discovery command to get their Spec, unless it is cached. Plugins
+// failing to describe their commands are not returned, a *DiscoveryError is returned for each of them instead. With
+// CacheOnly, plugins that are not cached are skipped silently.
+//
+// The compatibility of each plugin with the host is checked (see Spec.CheckCompatibility): incompatible plugins are
+// not returned, an *IncompatibleError is returned instead. A *CompatibilityWarning is returned for plugins that are
+// returned, but may not work as expected.
+func (m *Manager) Discover(ctx context.Context) ([]Plugin, []error) {
+	plugins, err := m.Find()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	hostVersion := m.HostVersion
+	if hostVersion == "" {
+		hostVersion = version.GetVersion().GitVersion
+	}
+
+	discovered := make([]Plugin, 0, len(plugins))
+	errs := []error{}
+	for _, p := range plugins {
+		spec, ok := m.Cache.get(p.Path)
+		if !ok {
+			if m.CacheOnly {
+				continue
+			}
+			spec, err = m.discoverSpec(ctx, p.Path)
+			if err != nil {
+				errs = append(errs, &DiscoveryError{Path: p.Path, Err: err})
+				continue
+			}
+			if err := m.Cache.put(p.Path, spec); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cache commands of plugin %s: %w", p.Path, err))
+			}
+		}
+
+		if err := spec.CheckCompatibility(p.Path, hostVersion); err != nil {
+			errs = append(errs, err)
+			var incompatibleErr *IncompatibleError
+			if errors.As(err, &incompatibleErr) {
+				continue
+			}
+		}
+		p.Spec = spec
+		discovered = append(discovered, p)
+	}
+	return discovered, errs
+}
+
+func (m *Manager) discoverSpec(ctx context.Context, path string) (Spec, error) {
+	timeout := m.DiscoveryTimeout
+	if timeout == 0 {
+		timeout = DefaultDiscoveryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path, DiscoveryCommandName) //nolint:gosec // Executing plugins is intended.
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return Spec{}, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return Spec{}, err
+	}
+
+	if err := ValidateDiscoveryOutput(stdout.Bytes()); err != nil {
+		return Spec{}, fmt.Errorf("invalid discovery output: %w", err)
+	}
+	spec := Spec{}
+	if err := json.Unmarshal(stdout.Bytes(), &spec); err != nil {
+		return Spec{}, fmt.Errorf("invalid discovery output: %w", err)
+	}
+	return spec, nil
+}
+
+// Mount adds the commands of the plugins to rootCmd. The subcommands of a plugin's root command are added to rootCmd,
+// merging into existing commands that are not runnable themselves, e.g. a plugin providing "create cluster aws"
+// extends the host's "create" command. Built-in commands like "help" and "version" are not added.
+//
+// Commands, aliases and flags conflicting with those of rootCmd or a previously mounted plugin are handled according
+// to ConflictPolicy (see MergeSpecs), each Conflict is returned as an error. With ConflictPolicyError, no commands are
+// added if there are conflicts and a *MergeError is returned.
+//
+// Running a mounted command executes the plugin with the command's path, the flags that were set and the arguments.
+func (m *Manager) Mount(rootCmd *cobra.Command, plugins ...Plugin) []error {
+	policy := m.ConflictPolicy
+	if policy == "" {
+		policy = ConflictPolicyFirstWins
+	}
+	merged, conflicts := mergeSpecs(SpecFromCommand(rootCmd), policy, plugins...)
+	if policy == ConflictPolicyError && len(conflicts) > 0 {
+		return []error{&MergeError{Conflicts: conflicts}}
+	}
+
+	errs := []error{}
+	for _, conflict := range conflicts {
+		errs = append(errs, conflict)
+	}
+	byOrigin := map[string]Plugin{}
+	for _, p := range plugins {
+		byOrigin[pluginOrigin(p)] = p
+	}
+	return append(errs, m.mount(rootCmd, merged, byOrigin, nil)...)
+}
+
+// mount adds the commands of plugins in node's subtree to cmd, the command node describes. pendingFlags are the
+// persistent flags plugins added to commands of other origins, by origin. They are added to the plugins' commands
+// instead, so they don't apply to the commands of other origins.
+func (m *Manager) mount(
+	cmd *cobra.Command, node *mergeNode, plugins map[string]Plugin, pendingFlags map[string][]FlagSpec,
+) []error {
+	pending := map[string][]FlagSpec{}
+	for origin, flags := range pendingFlags {
+		pending[origin] = flags
+	}
+	for _, flag := range node.spec.PersistentFlags {
+		if origin := node.flagOrigin(flag.Name); origin != node.origin {
+			pending[origin] = append(pending[origin][:len(pending[origin]):len(pending[origin])], flag)
+		}
+	}
+	for _, group := range node.spec.Groups {
+		if !cmd.ContainsGroup(group.ID) {
+			cmd.AddGroup(&cobra.Group{ID: group.ID, Title: group.Title})
+		}
+	}
+
+	errs := []error{}
+	for _, child := range node.children {
+		var existing *cobra.Command
+		for _, c := range cmd.Commands() {
+			if c.Name() == child.name() {
+				existing = c
+				break
+			}
+		}
+		if child.origin == hostOrigin {
+			if existing != nil {
+				errs = append(errs, m.mount(existing, child, plugins, pending)...)
+			}
+			continue
+		}
+		if existing != nil {
+			// a command of the host that is not part of its spec, e.g. a hidden built-in command
+			errs = append(errs, fmt.Errorf("command %q of %s conflicts with an existing command",
+				strings.TrimSpace(cmd.CommandPath()+" "+child.name()), child.origin))
+			continue
+		}
+
+		p := plugins[child.origin]
+		spec := child.spec
+		spec.PersistentFlags = nil
+		for _, flag := range child.spec.PersistentFlags {
+			if child.flagOrigin(flag.Name) == child.origin {
+				spec.PersistentFlags = append(spec.PersistentFlags, flag)
+			}
+		}
+		for _, flag := range pending[child.origin] {
+			if !hasFlag(spec.PersistentFlags, flag.Name) && !hasFlag(spec.LocalFlags, flag.Name) {
+				spec.PersistentFlags = append(spec.PersistentFlags, flag)
+			}
+		}
+		newCmd := spec.ToCommand(runPlugin(p, m.Env, m.Output))
+		if newCmd.GroupID != "" && !cmd.ContainsGroup(newCmd.GroupID) {
+			newCmd.GroupID = ""
+		}
+		m.forwardCompletion(newCmd, p)
+		cmd.AddCommand(newCmd)
+
+		// the subcommands inherit the pending flags
+		childPending := map[string][]FlagSpec{}
+		for origin, flags := range pending {
+			if origin != child.origin {
+				childPending[origin] = flags
+			}
+		}
+		errs = append(errs, m.mount(newCmd, child, plugins, childPending)...)
+	}
+	return errs
+}
+
+// forwardCompletion makes cmd and its subcommands complete arguments and flag values by executing the plugin's
+// completion command, so dynamic completions of the plugin work in the host, too.
+func (m *Manager) forwardCompletion(cmd *cobra.Command, p Plugin) {
+	pluginRootFlags := map[string]bool{}
+	for _, flagSpec := range p.Spec.Commands.PersistentFlags {
+		pluginRootFlags[flagSpec.Name] = true
+	}
+
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		if c.Runnable() {
+			c.ValidArgsFunction = m.completePlugin(p, pluginRootFlags, "")
+		}
+		addFlagCompletion := func(flag *pflag.Flag) {
+			// fails for flags with static completion values, which are already registered by CommandSpec.ToCommand
+			_ = c.RegisterFlagCompletionFunc(flag.Name, m.completePlugin(p, pluginRootFlags, flag.Name))
+		}
+		c.PersistentFlags().VisitAll(addFlagCompletion)
+		c.Flags().VisitAll(addFlagCompletion)
+		for _, subCmd := range c.Commands() {
+			visit(subCmd)
+		}
+	}
+	visit(cmd)
+}
+
+// completePlugin returns a completion function executing the plugin's completion command with the invoked command's
+// path, flags and arguments. If flagName is set, the value of that flag is completed, otherwise the next argument.
+func (m *Manager) completePlugin(
+	p Plugin, pluginRootFlags map[string]bool, flagName string,
+) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timeout := m.DiscoveryTimeout
+		if timeout == 0 {
+			timeout = DefaultDiscoveryTimeout
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// no "--" before the arguments, the plugin would not complete flags after it
+		completeArgs := append([]string{cobra.ShellCompRequestCmd}, pluginCommandArgs(cmd, pluginRootFlags)...)
+		completeArgs = append(completeArgs, args...)
+		if flagName != "" {
+			completeArgs = append(completeArgs, "--"+flagName)
+		}
+		completeArgs = append(completeArgs, toComplete)
+
+		stdout := bytes.Buffer{}
+		pluginCmd := exec.CommandContext(ctx, p.Path, completeArgs...) //nolint:gosec // Executing plugins is intended.
+		pluginCmd.Stdout = &stdout
+		pluginCmd.Env = append(os.Environ(), m.Env...)
+		if err := pluginCmd.Run(); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return parseCompletions(stdout.String())
+	}
+}
+
+// parseCompletions parses the output of cobra's completion command: one completion per line, followed by the
+// directive prefixed with ":".
+func parseCompletions(completionOutput string) ([]string, cobra.ShellCompDirective) {
+	lines := strings.Split(strings.TrimRight(completionOutput, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	completions := []string{}
+	for _, line := range lines[:len(lines)-1] {
+		if line != "" {
+			completions = append(completions, line)
+		}
+	}
+	return completions, cobra.ShellCompDirective(directive)
+}
+
+// runPlugin returns a run function executing the plugin with the invoked command's path, flags and arguments.
+func runPlugin(p Plugin, env []string, out output.Output) func(cmd *cobra.Command, args []string) error {
+	// flags of the host's root command are only passed if the plugin's root command declares them too
+	pluginRootFlags := map[string]bool{}
+	for _, flagSpec := range p.Spec.Commands.PersistentFlags {
+		pluginRootFlags[flagSpec.Name] = true
+	}
+
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		pluginArgs := pluginArgs(cmd, args, pluginRootFlags)
+		pluginCmd := exec.CommandContext(ctx, p.Path, pluginArgs...) //nolint:gosec // Executing plugins is intended.
+		pluginCmd.Stdin = cmd.InOrStdin()
+		pluginCmd.Stdout = cmd.OutOrStdout()
+		pluginCmd.Stderr = cmd.ErrOrStderr()
+		pluginCmd.Env = append(os.Environ(), env...)
+
+		err := runWithEventStream(pluginCmd, p, out)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			// the plugin already reported the error
+			cmd.SilenceErrors = true
+			return &ExitError{Plugin: p.Name, Code: exitErr.ExitCode()}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+}
+
+// runWithEventStream runs the plugin, rendering its output events with out if it supports the event stream.
+func runWithEventStream(pluginCmd *exec.Cmd, p Plugin, out output.Output) error {
+	// passing additional file descriptors is not supported on Windows
+	if out == nil || !p.Spec.Capabilities.Has(CapabilityEventStream) || runtime.GOOS == "windows" {
+		return pluginCmd.Run()
+	}
+
+	events, eventsWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	pluginCmd.ExtraFiles = []*os.File{eventsWriter}
+	// file descriptors 0-2 are stdin, stdout and stderr
+	pluginCmd.Env = append(pluginCmd.Env, fmt.Sprintf("%s=%d", EnvEventStreamFD, 3)) //nolint:gomnd // First extra file.
+
+	err = pluginCmd.Start()
+	// only the plugin writes events, the pipe is closed once it exits
+	eventsWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	replayed := make(chan struct{})
+	go func() {
+		defer close(replayed)
+		_ = output.ReplayEvents(events, out)
+	}()
+	err = pluginCmd.Wait()
+	select {
+	case <-replayed:
+	case <-time.After(time.Second):
+		// the pipe is still open, e.g. because it was inherited by a process started by the plugin
+		events.Close()
+		<-replayed
+	}
+	return err
+}
+
+// pluginArgs reconstructs the arguments the plugin is invoked with: the path of the command (without the host's root
+// command), the flags that were set and the positional arguments.
+func pluginArgs(cmd *cobra.Command, args []string, pluginRootFlags map[string]bool) []string {
+	result := pluginCommandArgs(cmd, pluginRootFlags)
+	if len(args) > 0 {
+		result = append(result, "--")
+		result = append(result, args...)
+	}
+	return result
+}
+
+// pluginCommandArgs returns the path of the command (without the host's root command) and the flags that were set.
+func pluginCommandArgs(cmd *cobra.Command, pluginRootFlags map[string]bool) []string {
+	path := []string{}
+	// flags that are defined by the plugin, i.e. on the mounted commands, not on the host's commands
+	pluginFlags := map[string]bool{}
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if c.Annotations[pluginNamespaceAnnotation] == "true" {
+			continue
+		}
+		path = append([]string{c.Name()}, path...)
+		c.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+			pluginFlags[flag.Name] = true
+		})
+	}
+
+	result := append([]string{}, path...)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if !pluginFlags[flag.Name] && !pluginRootFlags[flag.Name] {
+			return
+		}
+		if value, ok := flag.Value.(*typedFlagValue); ok {
+			// repeated flags, e.g. string slices, are passed as often as they were given
+			for _, value := range value.rawValues {
+				result = append(result, fmt.Sprintf("--%s=%s", flag.Name, value))
+			}
+			return
+		}
+		result = append(result, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+	return result
+}
+
+// hostExecutable returns the resolved path of the running executable, empty if it cannot be determined.
+func hostExecutable() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return ""
+	}
+	return executable
+}
+
+func executableSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.HasSuffix(path, ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,330 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/root"
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/symlinks"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+// buildTestPlugin builds testdata/plugin_main.go as "dkp-example" and returns the directory containing it.
+func buildTestPlugin(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command( //nolint:gosec // Building test binary into temporary directory.
+		"go", "build",
+		"-o", filepath.Join(dir, "dkp-example"),
+		"testdata/plugin_main.go")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return dir
+}
+
+func newTestHost() *cobra.Command {
+	rootCmd := &cobra.Command{Use: "dkp", SilenceUsage: true}
+	rootCmd.PersistentFlags().Int("verbose", 0, "verbosity")
+	createCmd := &cobra.Command{Use: "create"}
+	createCmd.AddCommand(&cobra.Command{Use: "bootstrap", Run: func(cmd *cobra.Command, args []string) {}})
+	rootCmd.AddCommand(createCmd)
+	return rootCmd
+}
+
+func TestManager(t *testing.T) {
+	dir := buildTestPlugin(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-notexecutable"), []byte{}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-broken"), []byte("#!/bin/sh\nexit 1\n"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other-tool"), []byte("#!/bin/sh\n"), 0o700))
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+
+	found, err := manager.Find()
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "broken", found[0].Name)
+	assert.Equal(t, "example", found[1].Name)
+	assert.Equal(t, filepath.Join(dir, "dkp-example"), found[1].Path)
+
+	plugins, errs := manager.Discover(context.Background())
+	require.Len(t, errs, 1)
+	discoveryErr := &plugin.DiscoveryError{}
+	require.ErrorAs(t, errs[0], &discoveryErr)
+	assert.Equal(t, filepath.Join(dir, "dkp-broken"), discoveryErr.Path)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, "dkp-example", plugins[0].Spec.Commands.Use)
+
+	rootCmd := newTestHost()
+	require.Empty(t, manager.Mount(rootCmd, plugins...))
+
+	run := func(args ...string) (string, error) {
+		out := bytes.Buffer{}
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&out)
+		rootCmd.SetArgs(args)
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("greet", "--greeting", "hi", "--greeting=hello", "--global", "--verbose", "1", "world")
+	require.NoError(t, err)
+	assert.Equal(t, `"greet" "--global=true" "--greeting=hi" "--greeting=hello" "--" "world"`, out)
+
+	// merged into the host's create command
+	out, err = run("create", "cluster", "example")
+	require.NoError(t, err)
+	assert.Equal(t, `"create" "cluster" "example"`, out)
+	out, err = run("create", "bootstrap")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	out, err = run("fail")
+	exitErr := &plugin.ExitError{}
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.Code)
+	assert.Equal(t, "failing", out)
+	// the plugin's command groups are added to the host
+	assert.True(t, rootCmd.ContainsGroup("example"))
+
+	// without host output, the plugin writes to stdout and stderr itself
+	out, err = run("events")
+	require.NoError(t, err)
+	assert.Regexp(t, `INF  • working...\n.* INF  ✓ working\nresult\n`, out)
+
+	// with host output, the plugin's output is rendered by the host
+	hostOut, hostErrOut := bytes.Buffer{}, bytes.Buffer{}
+	manager.Output = output.NewNonInteractiveShell(&hostOut, &hostErrOut, 0)
+	rootCmd = newTestHost()
+	require.Empty(t, manager.Mount(rootCmd, plugins...))
+	out, err = run("events")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	assert.Equal(t, "result\n", hostOut.String())
+	assert.Regexp(t, `INF  • working...\n.* INF  ✓ working\n`, hostErrOut.String())
+}
+
+func TestManagerSkipsHostSymlinks(t *testing.T) {
+	dir := buildTestPlugin(t)
+	require.NoError(t, os.Rename(filepath.Join(dir, "dkp-example"), filepath.Join(dir, "example")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "example"), filepath.Join(dir, "dkp-example")))
+
+	// symlinks for the host's commands point to the running executable, the test binary
+	hostCmd := &cobra.Command{Use: "dkp"}
+	createCmd := &cobra.Command{Use: "create"}
+	createCmd.AddCommand(&cobra.Command{Use: "cluster", Run: func(cmd *cobra.Command, args []string) {}})
+	hostCmd.AddCommand(createCmd)
+	created, err := symlinks.Create(hostCmd, dir, symlinks.Options{})
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.FileExists(t, filepath.Join(dir, "dkp-create-cluster"))
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	found, err := manager.Find()
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "example", found[0].Name)
+	assert.Equal(t, filepath.Join(dir, "dkp-example"), found[0].Path)
+}
+
+func TestManagerConflicts(t *testing.T) {
+	manager := plugin.NewManager("dkp")
+	rootCmd := newTestHost()
+	rootCmd.AddCommand(&cobra.Command{Use: "greet", Run: func(cmd *cobra.Command, args []string) {}})
+
+	pluginCmd := &cobra.Command{Use: "dkp-example"}
+	pluginCmd.AddCommand(&cobra.Command{Use: "greet", Run: func(cmd *cobra.Command, args []string) {}})
+	pluginCmd.AddCommand(&cobra.Command{Use: "version", Run: func(cmd *cobra.Command, args []string) {}})
+	pluginCmd.AddCommand(&cobra.Command{Use: "other", Run: func(cmd *cobra.Command, args []string) {}})
+
+	errs := manager.Mount(rootCmd, plugin.Plugin{
+		Name: "example",
+		Path: "/plugins/dkp-example",
+		Spec: plugin.Spec{Commands: plugin.SpecFromCommand(pluginCmd)},
+	})
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0],
+		`command "greet" of plugin example (/plugins/dkp-example) conflicts with host (skipped)`)
+	assert.Equal(t, []string{"create", "greet", "other"}, commandNames(rootCmd))
+}
+
+func TestManagerConflictPolicies(t *testing.T) {
+	pluginCmd := &cobra.Command{Use: "dkp-example"}
+	pluginCmd.AddCommand(&cobra.Command{Use: "greet", Run: func(cmd *cobra.Command, args []string) {}})
+	p := plugin.Plugin{
+		Name: "example",
+		Path: "/plugins/dkp-example",
+		Spec: plugin.Spec{Commands: plugin.SpecFromCommand(pluginCmd)},
+	}
+	newHost := func() *cobra.Command {
+		rootCmd := newTestHost()
+		rootCmd.AddCommand(&cobra.Command{Use: "greet", Run: func(cmd *cobra.Command, args []string) {}})
+		return rootCmd
+	}
+
+	manager := plugin.NewManager("dkp")
+	manager.ConflictPolicy = plugin.ConflictPolicyError
+	rootCmd := newHost()
+	errs := manager.Mount(rootCmd, p)
+	require.Len(t, errs, 1)
+	mergeErr := &plugin.MergeError{}
+	require.ErrorAs(t, errs[0], &mergeErr)
+	assert.Len(t, mergeErr.Conflicts, 1)
+	assert.Equal(t, []string{"create", "greet"}, commandNames(rootCmd))
+
+	manager.ConflictPolicy = plugin.ConflictPolicyNamespace
+	rootCmd = newHost()
+	errs = manager.Mount(rootCmd, p)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0],
+		`command "greet" of plugin example (/plugins/dkp-example) conflicts with host (moved to "example greet")`)
+	assert.Equal(t, []string{"create", "example", "greet"}, commandNames(rootCmd))
+	cmd, _, err := rootCmd.Find([]string{"example", "greet"})
+	require.NoError(t, err)
+	assert.Equal(t, "dkp example greet", cmd.CommandPath())
+}
+
+func TestManagerShorthandConflicts(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1" = "_plugin_commands" ]; then
+	echo '{"commands": {"use": "dkp-example", "sub_commands": [{"use": "get", "runnable": true,
+		"local_flags": [{"name": "values", "shorthand": "v", "type": "string", "usage": "values"}]}]}}'
+else
+	printf "%s " "$@"
+fi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-example"), []byte(script), 0o700))
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	plugins, errs := manager.Discover(context.Background())
+	require.Empty(t, errs)
+
+	out := bytes.Buffer{}
+	rootCmd, _ := root.NewCommand(&out, io.Discard)
+	errs = manager.Mount(rootCmd, plugins...)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `shorthand -v of flag --values of command "get" of plugin example (`+
+		filepath.Join(dir, "dkp-example")+`) conflicts with host (shorthand dropped)`)
+
+	// -v is the verbosity flag of the host, the plugin's flag is only available as --values
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"get", "-v", "2", "--values", "a"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, out.String(), "--values=a")
+}
+
+func commandNames(cmd *cobra.Command) []string {
+	names := []string{}
+	for _, subCmd := range cmd.Commands() {
+		names = append(names, subCmd.Name())
+	}
+	return names
+}
+
+func TestManagerCompatibility(t *testing.T) {
+	dir := t.TempDir()
+	for name, spec := range map[string]string{
+		"dkp-incompatible": `{"protocol_version": 1, "min_host_version": "v2.5.0", "commands": {"use": "a"}}`,
+		"dkp-newer":        `{"protocol_version": 99, "commands": {"use": "b"}}`,
+		"dkp-compatible":   `{"protocol_version": 1, "min_host_version": "v2.4.0", "commands": {"use": "c"}}`,
+	} {
+		script := "#!/bin/sh\necho '" + spec + "'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0o700))
+	}
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	manager.HostVersion = "v2.4.1"
+
+	plugins, errs := manager.Discover(context.Background())
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "compatible", plugins[0].Name)
+	assert.Equal(t, "newer", plugins[1].Name)
+
+	require.Len(t, errs, 2)
+	incompatibleErr := &plugin.IncompatibleError{}
+	assert.ErrorAs(t, errs[0], &incompatibleErr)
+	compatibilityWarning := &plugin.CompatibilityWarning{}
+	assert.ErrorAs(t, errs[1], &compatibilityWarning)
+}
+
+func TestManagerEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1" = "_plugin_commands" ]; then
+	echo '{"commands": {"use": "dkp-env", "sub_commands": [{"use": "print", "runnable": true}]}}'
+else
+	printf "%s" "$DKP_CLI_VERBOSITY"
+fi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dkp-env"), []byte(script), 0o700))
+
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	manager.Env = []string{"DKP_CLI_VERBOSITY=3"}
+
+	plugins, errs := manager.Discover(context.Background())
+	require.Empty(t, errs)
+	rootCmd := newTestHost()
+	require.Empty(t, manager.Mount(rootCmd, plugins...))
+
+	out := bytes.Buffer{}
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"print"})
+	require.NoError(t, rootCmd.Execute())
+	assert.Equal(t, "3", out.String())
+}
+
+func TestManagerCompletion(t *testing.T) {
+	dir := buildTestPlugin(t)
+	manager := plugin.NewManager("dkp", dir)
+	manager.DisablePath = true
+	plugins, errs := manager.Discover(context.Background())
+	require.Empty(t, errs)
+
+	complete := func(args ...string) string {
+		rootCmd := newTestHost()
+		require.Empty(t, manager.Mount(rootCmd, plugins...))
+		out := bytes.Buffer{}
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(io.Discard)
+		rootCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+		require.NoError(t, rootCmd.Execute())
+		return out.String()
+	}
+
+	// dynamic completions are forwarded to the plugin, including the flags that were set
+	assert.Equal(t, "alice\tfriend of de\nbob\n:4\n", complete("greet", "--language", "de", ""))
+	assert.Equal(t, "formal\ncasual\nworld\n:2\n", complete("greet", "world", "--style", ""))
+	// static completions are handled by the host
+	assert.Equal(t, "en\nde\n:4\n", complete("greet", "--language", ""))
+	assert.Equal(t, "tmpl\n:8\n", complete("greet", "--template", ""))
+
+	flags := map[string]plugin.FlagSpec{}
+	for _, spec := range plugins[0].Spec.Commands.SubCommands {
+		if spec.Use == "greet NAME" {
+			for _, flag := range spec.LocalFlags {
+				flags[flag.Name] = flag
+			}
+		}
+	}
+	assert.Equal(t, &plugin.FlagCompletion{Values: []string{"en", "de"}}, flags["language"].Completion)
+	assert.Equal(t, &plugin.FlagCompletion{FileExtensions: []string{"tmpl"}}, flags["template"].Completion)
+	assert.Nil(t, flags["style"].Completion)
+}
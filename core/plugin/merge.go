@@ -0,0 +1,456 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy defines how MergeSpecs handles conflicting definitions of commands, aliases and flags.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyError makes MergeSpecs return a *MergeError if there are conflicts.
+	ConflictPolicyError ConflictPolicy = "error"
+	// ConflictPolicyFirstWins keeps the first definition, later conflicting definitions are skipped.
+	ConflictPolicyFirstWins ConflictPolicy = "first-wins"
+	// ConflictPolicyNamespace moves top-level commands of a plugin with conflicts under a command named after the
+	// plugin, e.g. "konvoy create cluster" instead of "create cluster". Conflicts that remain, e.g. flags conflicting
+	// with flags of the root command, are resolved like with ConflictPolicyFirstWins.
+	ConflictPolicyNamespace ConflictPolicy = "namespace"
+)
+
+// ConflictKind is the kind of definition a Conflict is about.
+type ConflictKind string
+
+const (
+	ConflictKindCommand ConflictKind = "command"
+	ConflictKindAlias   ConflictKind = "alias"
+	ConflictKindFlag    ConflictKind = "flag"
+	// ConflictKindShorthand is a flag using the shorthand of another flag of the same command, e.g. an inherited
+	// flag. The conflicting flag is kept without shorthand.
+	ConflictKindShorthand ConflictKind = "shorthand"
+)
+
+// hostOrigin is the origin of the commands of the root spec passed to MergeSpecs.
+const hostOrigin = "host"
+
+// pluginNamespaceAnnotation marks the commands created by ConflictPolicyNamespace. They are not part of the path of
+// the command a plugin is invoked with.
+const pluginNamespaceAnnotation = "dkp_cli_plugin_namespace"
+
+// Conflict describes two definitions of the same command, alias or flag.
+type Conflict struct {
+	Kind ConflictKind
+	// Command is the path of the command (without the root command), e.g. "create cluster".
+	Command string
+	// Name is the conflicting alias or flag, empty for commands.
+	Name string
+	// Shorthand is the conflicting shorthand of the flag, only set for ConflictKindShorthand.
+	Shorthand string
+	// Origin is where the definition that was kept comes from, e.g. "host" or "plugin konvoy (/usr/bin/dkp-konvoy)".
+	Origin string
+	// ConflictingOrigin is where the conflicting definition comes from.
+	ConflictingOrigin string
+	// Resolution describes how the conflict was resolved, e.g. "skipped".
+	Resolution string
+}
+
+func (c Conflict) Error() string {
+	subject := fmt.Sprintf("command %q", c.Command)
+	switch c.Kind {
+	case ConflictKindAlias:
+		subject = fmt.Sprintf("alias %q of %s", c.Name, subject)
+	case ConflictKindFlag:
+		subject = fmt.Sprintf("flag --%s of %s", c.Name, subject)
+	case ConflictKindShorthand:
+		subject = fmt.Sprintf("shorthand -%s of flag --%s of %s", c.Shorthand, c.Name, subject)
+	}
+	return fmt.Sprintf("%s of %s conflicts with %s (%s)", subject, c.ConflictingOrigin, c.Origin, c.Resolution)
+}
+
+// MergeError is returned by MergeSpecs with ConflictPolicyError if there are conflicts.
+type MergeError struct {
+	Conflicts []Conflict
+}
+
+func (e *MergeError) Error() string {
+	messages := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		messages = append(messages, conflict.Error())
+	}
+	return fmt.Sprintf("%d conflicts: %s", len(e.Conflicts), strings.Join(messages, "; "))
+}
+
+// MergeSpecs adds the commands of the plugins to root like Manager.Mount does: the subcommands of a plugin's root
+// command are added to root, merging into existing commands that are not runnable, and the persistent flags of the
+// plugin's root command are added to each of them. Built-in commands like "help" and "version" are not added.
+//
+// The following definitions conflict with existing definitions of the host (root) or of previous plugins:
+//   - commands with the same path if one of them is runnable
+//   - aliases that are the name or alias of another command with the same parent
+//   - persistent flags of merged commands and flags shadowing inherited flags, if their types differ
+//   - flags using the shorthand of another flag of the command, including inherited flags and, for persistent
+//     flags, flags of subcommands.
+//
+// Conflicts are resolved according to the policy and all of them are returned. Flags with conflicting shorthands are
+// kept without their shorthand. With ConflictPolicyError, the merged
+// spec is returned like with ConflictPolicyFirstWins, along with a *MergeError.
+func MergeSpecs(root CommandSpec, policy ConflictPolicy, plugins ...Plugin) (CommandSpec, []Conflict, error) {
+	rootNode, conflicts := mergeSpecs(root, policy, plugins...)
+	if policy == ConflictPolicyError && len(conflicts) > 0 {
+		return rootNode.toSpec(), conflicts, &MergeError{Conflicts: conflicts}
+	}
+	return rootNode.toSpec(), conflicts, nil
+}
+
+// mergeSpecs is like MergeSpecs, but returns the merged commands with their origins.
+func mergeSpecs(root CommandSpec, policy ConflictPolicy, plugins ...Plugin) (*mergeNode, []Conflict) {
+	rootNode := newMergeNode(root, hostOrigin)
+	conflicts := []Conflict{}
+
+	for _, p := range plugins {
+		origin := pluginOrigin(p)
+		for _, spec := range p.Spec.Commands.SubCommands {
+			if builtinCommands[commandName(spec.Use)] {
+				continue
+			}
+			spec.PersistentFlags = append(spec.PersistentFlags[:0:0], spec.PersistentFlags...)
+			for _, flag := range p.Spec.Commands.PersistentFlags {
+				if !hasFlag(spec.PersistentFlags, flag.Name) && !hasFlagOfType(rootNode.spec.PersistentFlags, flag) {
+					spec.PersistentFlags = append(spec.PersistentFlags, flag)
+				}
+			}
+			// like cobra, the host must know the group of a command
+			if groupID := spec.GroupID; groupID != "" && !hasGroup(rootNode.spec.Groups, groupID) {
+				spec.GroupID = ""
+				for _, group := range p.Spec.Commands.Groups {
+					if group.ID == groupID {
+						rootNode.spec.Groups = append(rootNode.spec.Groups, group)
+						spec.GroupID = groupID
+					}
+				}
+			}
+			node := newMergeNode(spec, origin)
+
+			merged := rootNode.clone()
+			cmdConflicts := merge(merged, node.clone(), "", rootNode.inheritedFlags(nil))
+			if len(cmdConflicts) == 0 || policy != ConflictPolicyNamespace {
+				rootNode = merged
+				conflicts = append(conflicts, cmdConflicts...)
+				continue
+			}
+
+			// retry under a command named after the plugin
+			namespace := &mergeNode{
+				spec: CommandSpec{
+					Use:         p.Name,
+					Short:       fmt.Sprintf("Commands of plugin %s", p.Name),
+					Annotations: map[string]string{pluginNamespaceAnnotation: "true"},
+					Groups:      p.Spec.Commands.Groups,
+				},
+				origin:   origin,
+				children: []*mergeNode{node},
+			}
+			namespaceConflicts := merge(rootNode, namespace, "", rootNode.inheritedFlags(nil))
+			resolution := fmt.Sprintf("moved to %q", p.Name+" "+commandName(spec.Use))
+			for _, conflict := range namespaceConflicts {
+				if conflict.Kind == ConflictKindCommand && conflict.Command == p.Name {
+					resolution = "skipped"
+				}
+			}
+			for _, conflict := range cmdConflicts {
+				conflict.Resolution = resolution
+				conflicts = append(conflicts, conflict)
+			}
+			conflicts = append(conflicts, namespaceConflicts...)
+		}
+	}
+	return rootNode, conflicts
+}
+
+func pluginOrigin(p Plugin) string {
+	if p.Path == "" {
+		return "plugin " + p.Name
+	}
+	return fmt.Sprintf("plugin %s (%s)", p.Name, p.Path)
+}
+
+// mergeNode is a command being merged. Unlike CommandSpec, it keeps track of the origin of each definition.
+type mergeNode struct {
+	// spec is the command without subcommands, see children.
+	spec     CommandSpec
+	origin   string
+	children []*mergeNode
+	// flagOrigins are the origins of persistent flags that were merged into this command.
+	flagOrigins map[string]string
+}
+
+// inheritedFlag is a persistent flag of a parent command.
+type inheritedFlag struct {
+	spec   FlagSpec
+	origin string
+}
+
+func newMergeNode(spec CommandSpec, origin string) *mergeNode {
+	node := &mergeNode{spec: spec, origin: origin}
+	node.spec.SubCommands = nil
+	for _, subSpec := range spec.SubCommands {
+		node.children = append(node.children, newMergeNode(subSpec, origin))
+	}
+	return node
+}
+
+func (n *mergeNode) clone() *mergeNode {
+	result := &mergeNode{spec: n.spec, origin: n.origin, flagOrigins: map[string]string{}}
+	// slices that are modified when merging are copied, keeping nil slices nil
+	result.spec.Aliases = append(n.spec.Aliases[:0:0], n.spec.Aliases...)
+	result.spec.Groups = append(n.spec.Groups[:0:0], n.spec.Groups...)
+	result.spec.PersistentFlags = append(n.spec.PersistentFlags[:0:0], n.spec.PersistentFlags...)
+	result.spec.LocalFlags = append(n.spec.LocalFlags[:0:0], n.spec.LocalFlags...)
+	for name, origin := range n.flagOrigins {
+		result.flagOrigins[name] = origin
+	}
+	for _, child := range n.children {
+		result.children = append(result.children, child.clone())
+	}
+	return result
+}
+
+func (n *mergeNode) toSpec() CommandSpec {
+	result := n.spec
+	result.SubCommands = nil
+	for _, child := range n.children {
+		result.SubCommands = append(result.SubCommands, child.toSpec())
+	}
+	return result
+}
+
+func (n *mergeNode) name() string {
+	return commandName(n.spec.Use)
+}
+
+// child returns the subcommand with the given name or alias.
+func (n *mergeNode) child(name string) *mergeNode {
+	for _, child := range n.children {
+		if child.name() == name {
+			return child
+		}
+	}
+	for _, child := range n.children {
+		if containsString(child.spec.Aliases, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *mergeNode) flagOrigin(name string) string {
+	if origin, ok := n.flagOrigins[name]; ok {
+		return origin
+	}
+	return n.origin
+}
+
+// inheritedFlags returns the flags inherited by subcommands of n, given the flags inherited by n.
+func (n *mergeNode) inheritedFlags(inherited map[string]inheritedFlag) map[string]inheritedFlag {
+	result := map[string]inheritedFlag{}
+	for name, flag := range inherited {
+		result[name] = flag
+	}
+	for _, flag := range n.spec.PersistentFlags {
+		result[flag.Name] = inheritedFlag{spec: flag, origin: n.flagOrigin(flag.Name)}
+	}
+	return result
+}
+
+// merge adds node to parent, or merges it into the existing command with the same name. Conflicting definitions of
+// node are skipped. path is the path of parent.
+func merge(parent, node *mergeNode, path string, inherited map[string]inheritedFlag) []Conflict {
+	cmdPath := strings.TrimSpace(path + " " + node.name())
+	conflict := func(kind ConflictKind, name, origin string) Conflict {
+		return Conflict{
+			Kind: kind, Command: cmdPath, Name: name,
+			Origin: origin, ConflictingOrigin: node.origin, Resolution: "skipped",
+		}
+	}
+
+	existing := parent.child(node.name())
+	if existing == nil {
+		conflicts := []Conflict{}
+		var aliases []string
+		for _, alias := range node.spec.Aliases {
+			if other := parent.child(alias); other != nil {
+				conflicts = append(conflicts, conflict(ConflictKindAlias, alias, other.origin))
+				continue
+			}
+			aliases = append(aliases, alias)
+		}
+		node.spec.Aliases = aliases
+		conflicts = append(conflicts, dropShadowingFlags(node, cmdPath, inherited)...)
+		parent.children = append(parent.children, node)
+		return conflicts
+	}
+	if existing.name() != node.name() || existing.spec.Runnable || node.spec.Runnable {
+		return []Conflict{conflict(ConflictKindCommand, "", existing.origin)}
+	}
+
+	// both commands only group subcommands, merge them
+	conflicts := []Conflict{}
+	for _, flag := range node.spec.PersistentFlags {
+		if other, ok := findFlag(existing.spec.PersistentFlags, flag.Name); ok {
+			if other.Type != flag.Type {
+				conflicts = append(conflicts, conflict(ConflictKindFlag, flag.Name, existing.flagOrigin(flag.Name)))
+			}
+			continue
+		}
+		if other, ok := inherited[flag.Name]; ok && other.origin != node.origin && other.spec.Type != flag.Type {
+			conflicts = append(conflicts, conflict(ConflictKindFlag, flag.Name, other.origin))
+			continue
+		}
+		origin, ok := existing.shorthandOrigin(flag)
+		if !ok {
+			origin, ok = inheritedShorthandOrigin(inherited, flag, existing.spec.PersistentFlags, existing.spec.LocalFlags)
+		}
+		if ok && origin != node.origin {
+			c := conflict(ConflictKindShorthand, flag.Name, origin)
+			c.Shorthand, c.Resolution = flag.Shorthand, "shorthand dropped"
+			conflicts = append(conflicts, c)
+			flag.Shorthand = ""
+		}
+		existing.spec.PersistentFlags = append(existing.spec.PersistentFlags, flag)
+		if existing.flagOrigins == nil {
+			existing.flagOrigins = map[string]string{}
+		}
+		existing.flagOrigins[flag.Name] = node.origin
+	}
+	for _, group := range node.spec.Groups {
+		if !hasGroup(existing.spec.Groups, group.ID) {
+			existing.spec.Groups = append(existing.spec.Groups, group)
+		}
+	}
+
+	childInherited := existing.inheritedFlags(inherited)
+	for _, child := range node.children {
+		conflicts = append(conflicts, merge(existing, child, cmdPath, childInherited)...)
+	}
+	return conflicts
+}
+
+// dropShadowingFlags removes the flags of node and its subcommands that shadow an inherited flag of another origin
+// with a different type, and the shorthands of flags that are used by an inherited flag of another origin.
+func dropShadowingFlags(node *mergeNode, path string, inherited map[string]inheritedFlag) []Conflict {
+	conflicts := []Conflict{}
+	filter := func(flags []FlagSpec) []FlagSpec {
+		var result []FlagSpec
+		for _, flag := range flags {
+			other, ok := inherited[flag.Name]
+			if ok && other.origin != node.origin && other.spec.Type != flag.Type {
+				conflicts = append(conflicts, Conflict{
+					Kind: ConflictKindFlag, Command: path, Name: flag.Name,
+					Origin: other.origin, ConflictingOrigin: node.origin, Resolution: "skipped",
+				})
+				continue
+			}
+			result = append(result, flag)
+		}
+		return result
+	}
+	node.spec.PersistentFlags = filter(node.spec.PersistentFlags)
+	node.spec.LocalFlags = filter(node.spec.LocalFlags)
+
+	dropShorthands := func(flags []FlagSpec) {
+		for i, flag := range flags {
+			origin, ok := inheritedShorthandOrigin(inherited, flag, node.spec.PersistentFlags, node.spec.LocalFlags)
+			if ok && origin != node.origin {
+				conflicts = append(conflicts, Conflict{
+					Kind: ConflictKindShorthand, Command: path, Name: flag.Name, Shorthand: flag.Shorthand,
+					Origin: origin, ConflictingOrigin: node.origin, Resolution: "shorthand dropped",
+				})
+				flags[i].Shorthand = ""
+			}
+		}
+	}
+	dropShorthands(node.spec.PersistentFlags)
+	dropShorthands(node.spec.LocalFlags)
+
+	childInherited := node.inheritedFlags(inherited)
+	for _, child := range node.children {
+		conflicts = append(conflicts, dropShadowingFlags(child, path+" "+child.name(), childInherited)...)
+	}
+	return conflicts
+}
+
+// shorthandOrigin returns the origin of a flag of n or its subcommands that is not named like flag, but uses its
+// shorthand.
+func (n *mergeNode) shorthandOrigin(flag FlagSpec) (string, bool) {
+	if flag.Shorthand == "" {
+		return "", false
+	}
+	for _, flags := range [][]FlagSpec{n.spec.PersistentFlags, n.spec.LocalFlags} {
+		for _, other := range flags {
+			if other.Name != flag.Name && other.Shorthand == flag.Shorthand {
+				return n.flagOrigin(other.Name), true
+			}
+		}
+	}
+	for _, child := range n.children {
+		if origin, ok := child.shorthandOrigin(flag); ok {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// inheritedShorthandOrigin returns the origin of an inherited flag that is not named like flag, but uses its
+// shorthand. Like cobra, inherited flags shadowed by one of the command's own flags are ignored.
+func inheritedShorthandOrigin(
+	inherited map[string]inheritedFlag, flag FlagSpec, ownFlags ...[]FlagSpec,
+) (string, bool) {
+	if flag.Shorthand == "" {
+		return "", false
+	}
+	for name, other := range inherited {
+		if name == flag.Name || other.spec.Shorthand != flag.Shorthand {
+			continue
+		}
+		shadowed := false
+		for _, flags := range ownFlags {
+			shadowed = shadowed || hasFlag(flags, name)
+		}
+		if !shadowed {
+			return other.origin, true
+		}
+	}
+	return "", false
+}
+
+func findFlag(flags []FlagSpec, name string) (FlagSpec, bool) {
+	for _, flag := range flags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return FlagSpec{}, false
+}
+
+func hasFlag(flags []FlagSpec, name string) bool {
+	_, ok := findFlag(flags, name)
+	return ok
+}
+
+func hasFlagOfType(flags []FlagSpec, flag FlagSpec) bool {
+	other, ok := findFlag(flags, flag.Name)
+	return ok && other.Type == flag.Type
+}
+
+func hasGroup(groups []GroupSpec, id string) bool {
+	for _, group := range groups {
+		if group.ID == id {
+			return true
+		}
+	}
+	return false
+}
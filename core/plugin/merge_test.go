@@ -0,0 +1,193 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func TestMergeSpecs(t *testing.T) {
+	host := plugin.CommandSpec{
+		Use:             "dkp",
+		PersistentFlags: []plugin.FlagSpec{{Name: "verbose", Type: "int"}},
+		SubCommands: []plugin.CommandSpec{
+			{Use: "create", SubCommands: []plugin.CommandSpec{{Use: "bootstrap", Runnable: true}}},
+			{Use: "version", Runnable: true},
+		},
+	}
+	konvoy := plugin.Plugin{Name: "konvoy", Path: "/bin/dkp-konvoy", Spec: plugin.Spec{Commands: plugin.CommandSpec{
+		Use:             "dkp-konvoy",
+		PersistentFlags: []plugin.FlagSpec{{Name: "kubeconfig", Type: "string"}},
+		SubCommands: []plugin.CommandSpec{
+			{
+				Use:             "create",
+				PersistentFlags: []plugin.FlagSpec{{Name: "namespace", Type: "string"}},
+				SubCommands:     []plugin.CommandSpec{{Use: "cluster", Runnable: true}},
+			},
+			{Use: "version", Runnable: true},
+		},
+	}}}
+	kommander := plugin.Plugin{Name: "kommander", Path: "/bin/dkp-kommander", Spec: plugin.Spec{
+		Commands: plugin.CommandSpec{
+			Use:             "dkp-kommander",
+			PersistentFlags: []plugin.FlagSpec{{Name: "verbose", Type: "bool"}},
+			SubCommands: []plugin.CommandSpec{
+				{
+					Use:             "create",
+					PersistentFlags: []plugin.FlagSpec{{Name: "namespace", Type: "int"}},
+					SubCommands: []plugin.CommandSpec{
+						{Use: "cluster", Runnable: true},
+						{Use: "workspace", Aliases: []string{"ws", "bootstrap"}, Runnable: true},
+					},
+				},
+			},
+		},
+	}}
+
+	konvoyOrigin, kommanderOrigin := "plugin konvoy (/bin/dkp-konvoy)", "plugin kommander (/bin/dkp-kommander)"
+	conflicts := []plugin.Conflict{
+		{
+			Kind: plugin.ConflictKindFlag, Command: "create", Name: "namespace",
+			Origin: konvoyOrigin, ConflictingOrigin: kommanderOrigin, Resolution: "skipped",
+		},
+		{
+			Kind: plugin.ConflictKindFlag, Command: "create", Name: "verbose",
+			Origin: "host", ConflictingOrigin: kommanderOrigin, Resolution: "skipped",
+		},
+		{
+			Kind: plugin.ConflictKindCommand, Command: "create cluster",
+			Origin: konvoyOrigin, ConflictingOrigin: kommanderOrigin, Resolution: "skipped",
+		},
+		{
+			Kind: plugin.ConflictKindAlias, Command: "create workspace", Name: "bootstrap",
+			Origin: "host", ConflictingOrigin: kommanderOrigin, Resolution: "skipped",
+		},
+	}
+
+	t.Run("first wins", func(t *testing.T) {
+		merged, actual, err := plugin.MergeSpecs(host, plugin.ConflictPolicyFirstWins, konvoy, kommander)
+		require.NoError(t, err)
+		assert.Equal(t, conflicts, actual)
+		assert.Equal(t, plugin.CommandSpec{
+			Use:             "dkp",
+			PersistentFlags: []plugin.FlagSpec{{Name: "verbose", Type: "int"}},
+			SubCommands: []plugin.CommandSpec{
+				{
+					Use: "create",
+					PersistentFlags: []plugin.FlagSpec{
+						{Name: "namespace", Type: "string"},
+						{Name: "kubeconfig", Type: "string"},
+					},
+					SubCommands: []plugin.CommandSpec{
+						{Use: "bootstrap", Runnable: true},
+						{Use: "cluster", Runnable: true},
+						{Use: "workspace", Aliases: []string{"ws"}, Runnable: true},
+					},
+				},
+				{Use: "version", Runnable: true},
+			},
+		}, merged)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, actual, err := plugin.MergeSpecs(host, plugin.ConflictPolicyError, konvoy, kommander)
+		mergeErr := &plugin.MergeError{}
+		require.ErrorAs(t, err, &mergeErr)
+		assert.Equal(t, conflicts, mergeErr.Conflicts)
+		assert.Equal(t, conflicts, actual)
+		assert.Contains(t, err.Error(), `4 conflicts: flag --namespace of command "create" of `+kommanderOrigin+
+			" conflicts with "+konvoyOrigin+" (skipped);")
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		merged, actual, err := plugin.MergeSpecs(host, plugin.ConflictPolicyNamespace, konvoy, kommander)
+		require.NoError(t, err)
+		require.Len(t, actual, 5)
+		assert.Equal(t, `moved to "kommander create"`, actual[0].Resolution)
+		assert.Equal(t, `moved to "kommander create"`, actual[3].Resolution)
+		// the flag inherited from the root command conflicts in the namespace, too
+		assert.Equal(t, plugin.Conflict{
+			Kind: plugin.ConflictKindFlag, Command: "kommander create", Name: "verbose",
+			Origin: "host", ConflictingOrigin: kommanderOrigin, Resolution: "skipped",
+		}, actual[4])
+
+		require.Len(t, merged.SubCommands, 3)
+		namespace := merged.SubCommands[2]
+		assert.Equal(t, "kommander", namespace.Use)
+		require.Len(t, namespace.SubCommands, 1)
+		assert.Equal(t, []plugin.FlagSpec{{Name: "namespace", Type: "int"}}, namespace.SubCommands[0].PersistentFlags)
+		assert.Len(t, namespace.SubCommands[0].SubCommands, 2)
+	})
+}
+
+func TestMergeSpecsShorthands(t *testing.T) {
+	host := plugin.CommandSpec{
+		Use:             "dkp",
+		PersistentFlags: []plugin.FlagSpec{{Name: "verbose", Shorthand: "v", Type: "int"}},
+		SubCommands: []plugin.CommandSpec{
+			{Use: "create", SubCommands: []plugin.CommandSpec{{
+				Use:        "bootstrap",
+				LocalFlags: []plugin.FlagSpec{{Name: "namespace", Shorthand: "n", Type: "string"}},
+				Runnable:   true,
+			}}},
+		},
+	}
+	konvoy := plugin.Plugin{Name: "konvoy", Spec: plugin.Spec{Commands: plugin.CommandSpec{
+		Use: "dkp-konvoy",
+		SubCommands: []plugin.CommandSpec{
+			{
+				Use:             "create",
+				PersistentFlags: []plugin.FlagSpec{{Name: "name", Shorthand: "n", Type: "string"}},
+				SubCommands: []plugin.CommandSpec{{
+					Use:        "cluster",
+					LocalFlags: []plugin.FlagSpec{{Name: "values", Shorthand: "v", Type: "string"}},
+					Runnable:   true,
+				}},
+			},
+			{
+				Use: "get",
+				LocalFlags: []plugin.FlagSpec{
+					{Name: "values", Shorthand: "v", Type: "string"},
+					// shadows the inherited flag, so its shorthand is not used
+					{Name: "verbose", Type: "int"},
+				},
+				Runnable: true,
+			},
+		},
+	}}}
+
+	conflicts := []plugin.Conflict{
+		{
+			Kind: plugin.ConflictKindShorthand, Command: "create", Name: "name", Shorthand: "n",
+			Origin: "host", ConflictingOrigin: "plugin konvoy", Resolution: "shorthand dropped",
+		},
+		{
+			Kind: plugin.ConflictKindShorthand, Command: "create cluster", Name: "values", Shorthand: "v",
+			Origin: "host", ConflictingOrigin: "plugin konvoy", Resolution: "shorthand dropped",
+		},
+	}
+
+	merged, actual, err := plugin.MergeSpecs(host, plugin.ConflictPolicyFirstWins, konvoy)
+	require.NoError(t, err)
+	assert.Equal(t, conflicts, actual)
+	assert.Equal(t, `shorthand -v of flag --values of command "create cluster" of plugin konvoy conflicts with host `+
+		"(shorthand dropped)", actual[1].Error())
+
+	require.Len(t, merged.SubCommands, 2)
+	create := merged.SubCommands[0]
+	assert.Equal(t, []plugin.FlagSpec{{Name: "name", Type: "string"}}, create.PersistentFlags)
+	require.Len(t, create.SubCommands, 2)
+	assert.Equal(t, []plugin.FlagSpec{{Name: "values", Type: "string"}}, create.SubCommands[1].LocalFlags)
+	assert.Equal(t, "v", merged.SubCommands[1].LocalFlags[0].Shorthand)
+
+	_, _, err = plugin.MergeSpecs(host, plugin.ConflictPolicyError, konvoy)
+	mergeErr := &plugin.MergeError{}
+	require.ErrorAs(t, err, &mergeErr)
+	assert.Equal(t, conflicts, mergeErr.Conflicts)
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the plugin protocol implemented by this runtime. It is increased whenever the
+// discovery format or the way plugins are invoked changes.
+//
+// Changes to the discovery format must be backwards compatible: new fields are optional, so older hosts ignore them
+// and newer hosts handle their absence. Specs of plugins built before the protocol was versioned have protocol
+// version 0. All protocol versions up to ProtocolVersion are supported.
+const ProtocolVersion = 1
+
+// Capability is an optional feature supported by a plugin. Hosts only use capabilities the plugin announces, so
+// features can be added without breaking older plugins.
+type Capability string
+
+// Capabilities lists the capabilities of a plugin.
+type Capabilities []Capability
+
+// Has returns true if the capability is in the list.
+func (c Capabilities) Has(capability Capability) bool {
+	for _, existing := range c {
+		if existing == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilityEventStream means the plugin writes its output as events (see output.NewEventStreamOutput) to the file
+// descriptor given in the EnvEventStreamFD environment variable, if set by the host.
+const CapabilityEventStream Capability = "event-stream"
+
+// EnvEventStreamFD is the environment variable containing the file descriptor plugins with CapabilityEventStream
+// write output events to.
+const EnvEventStreamFD = "DKP_CLI_EVENT_STREAM_FD"
+
+// IncompatibleError is returned for plugins that cannot be used by the host.
+type IncompatibleError struct {
+	Path   string
+	Reason string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("plugin %s is incompatible: %s", e.Path, e.Reason)
+}
+
+// CompatibilityWarning is returned for plugins that can be used by the host, but may not work as expected.
+type CompatibilityWarning struct {
+	Path   string
+	Reason string
+}
+
+func (e *CompatibilityWarning) Error() string {
+	return fmt.Sprintf("plugin %s may not work as expected: %s", e.Path, e.Reason)
+}
+
+// CheckCompatibility checks whether the plugin described by the spec can be used by a host of the given version. It
+// returns an *IncompatibleError if the plugin must not be used and a *CompatibilityWarning if it can be used with
+// restrictions.
+//
+// Hosts of development versions (that are not valid semantic versions or v0.0.0) accept any minimum host version.
+func (s Spec) CheckCompatibility(path, hostVersion string) error {
+	name := path
+	if s.Name != "" {
+		name = fmt.Sprintf("%s (%s %s)", path, s.Name, s.Version)
+	}
+
+	if s.ProtocolVersion < 0 {
+		return &IncompatibleError{Path: name, Reason: fmt.Sprintf("invalid protocol version %d", s.ProtocolVersion)}
+	}
+
+	if s.MinHostVersion != "" {
+		host, err := parseVersion(hostVersion)
+		if err == nil && !host.isZero() {
+			required, err := parseVersion(s.MinHostVersion)
+			if err != nil {
+				return &IncompatibleError{Path: name, Reason: fmt.Sprintf(
+					"invalid minimum host version %q: %v", s.MinHostVersion, err,
+				)}
+			}
+			if host.compare(required) < 0 {
+				return &IncompatibleError{Path: name, Reason: fmt.Sprintf(
+					"requires host version %s or newer, this is %s", s.MinHostVersion, hostVersion,
+				)}
+			}
+		}
+	}
+
+	if s.ProtocolVersion > ProtocolVersion {
+		return &CompatibilityWarning{Path: name, Reason: fmt.Sprintf(
+			"protocol version %d is newer than the host's protocol version %d, consider upgrading the host",
+			s.ProtocolVersion, ProtocolVersion,
+		)}
+	}
+	return nil
+}
+
+// semanticVersion is a parsed semantic version like "v1.2.3-rc.1". Build metadata is ignored.
+type semanticVersion struct {
+	major, minor, patch int
+	// preRelease are the dot-separated pre-release identifiers, e.g. ["rc", "1"].
+	preRelease []string
+}
+
+func parseVersion(version string) (semanticVersion, error) {
+	v := strings.TrimPrefix(version, "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	result := semanticVersion{}
+	if i := strings.Index(v, "-"); i >= 0 {
+		result.preRelease = strings.Split(v[i+1:], ".")
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 { //nolint:gomnd // major.minor.patch
+		return semanticVersion{}, fmt.Errorf("%q is not a semantic version", version)
+	}
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return semanticVersion{}, fmt.Errorf("%q is not a semantic version", version)
+		}
+		numbers[i] = number
+	}
+	result.major, result.minor, result.patch = numbers[0], numbers[1], numbers[2]
+	return result, nil
+}
+
+func (v semanticVersion) isZero() bool {
+	return v.major == 0 && v.minor == 0 && v.patch == 0
+}
+
+// compare returns -1 if v is older than other, 1 if it is newer and 0 if they are equal. Pre-releases are older than
+// the release, pre-releases of the same release are compared by their identifiers as defined by semantic versioning:
+// numeric identifiers numerically, others lexically, and numeric identifiers are older than others.
+func (v semanticVersion) compare(other semanticVersion) int {
+	for _, diff := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if result := sign(diff); result != 0 {
+			return result
+		}
+	}
+	switch {
+	case len(v.preRelease) == 0 && len(other.preRelease) == 0:
+		return 0
+	case len(v.preRelease) == 0:
+		return 1
+	case len(other.preRelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.preRelease) && i < len(other.preRelease); i++ {
+		if result := compareIdentifiers(v.preRelease[i], other.preRelease[i]); result != 0 {
+			return result
+		}
+	}
+	// a larger set of identifiers is newer if all preceding identifiers are equal
+	return sign(len(v.preRelease) - len(other.preRelease))
+}
+
+func compareIdentifiers(a, b string) int {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return sign(aNumber - bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		spec         plugin.Spec
+		hostVersion  string
+		err          string
+		incompatible bool
+	}{
+		{name: "legacy plugin", spec: plugin.Spec{}, hostVersion: "v2.4.0"},
+		{
+			name:        "current plugin",
+			spec:        plugin.Spec{ProtocolVersion: plugin.ProtocolVersion, MinHostVersion: "v2.4.0"},
+			hostVersion: "v2.4.0",
+		},
+		{
+			name:        "host too old",
+			spec:        plugin.Spec{Name: "example", Version: "v1.0.0", MinHostVersion: "v2.4.0"},
+			hostVersion: "v2.3.9",
+			err: "plugin /dkp-example (example v1.0.0) is incompatible: " +
+				"requires host version v2.4.0 or newer, this is v2.3.9",
+			incompatible: true,
+		},
+		{
+			name:        "pre-release host",
+			spec:        plugin.Spec{MinHostVersion: "v2.4.0"},
+			hostVersion: "v2.4.0-rc.1",
+			err: "plugin /dkp-example is incompatible: " +
+				"requires host version v2.4.0 or newer, this is v2.4.0-rc.1",
+			incompatible: true,
+		},
+		{
+			name:        "newer pre-release host",
+			spec:        plugin.Spec{MinHostVersion: "v2.4.0-rc.9"},
+			hostVersion: "v2.4.0-rc.10",
+		},
+		{
+			name:        "older pre-release host",
+			spec:        plugin.Spec{MinHostVersion: "v2.4.0-rc.10"},
+			hostVersion: "v2.4.0-rc.9",
+			err: "plugin /dkp-example is incompatible: " +
+				"requires host version v2.4.0-rc.10 or newer, this is v2.4.0-rc.9",
+			incompatible: true,
+		},
+		{
+			name:         "invalid protocol version",
+			spec:         plugin.Spec{ProtocolVersion: -1},
+			hostVersion:  "v2.4.0",
+			err:          "plugin /dkp-example is incompatible: invalid protocol version -1",
+			incompatible: true,
+		},
+		{name: "development host", spec: plugin.Spec{MinHostVersion: "v2.4.0"}, hostVersion: "v0.0.0-dev"},
+		{
+			name:        "invalid minimum host version",
+			spec:        plugin.Spec{MinHostVersion: "latest"},
+			hostVersion: "v2.4.0",
+			err: `plugin /dkp-example is incompatible: ` +
+				`invalid minimum host version "latest": "latest" is not a semantic version`,
+			incompatible: true,
+		},
+		{
+			name:        "newer protocol",
+			spec:        plugin.Spec{ProtocolVersion: plugin.ProtocolVersion + 1},
+			hostVersion: "v2.4.0",
+			err: "plugin /dkp-example may not work as expected: " +
+				"protocol version 2 is newer than the host's protocol version 1, consider upgrading the host",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.spec.CheckCompatibility("/dkp-example", test.hostVersion)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, test.err)
+			incompatibleErr := &plugin.IncompatibleError{}
+			assert.Equal(t, test.incompatible, errors.As(err, &incompatibleErr))
+		})
+	}
+}
+
+func TestSpecSchemaEvolution(t *testing.T) {
+	// specs of plugins built before the protocol was versioned
+	spec := plugin.Spec{}
+	require.NoError(t, json.Unmarshal([]byte(`{"commands": {"use": "dkp-legacy"}}`), &spec))
+	assert.Equal(t, 0, spec.ProtocolVersion)
+	assert.NoError(t, spec.CheckCompatibility("/dkp-legacy", "v2.4.0"))
+
+	// specs of newer plugins with unknown fields and capabilities
+	spec = plugin.Spec{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"protocol_version": 1,
+		"capabilities": ["some-future-capability"],
+		"some_future_field": {"key": "value"},
+		"commands": {"use": "dkp-future", "some_future_command_field": true}
+	}`), &spec))
+	assert.Equal(t, "dkp-future", spec.Commands.Use)
+	assert.True(t, spec.Capabilities.Has("some-future-capability"))
+	assert.False(t, spec.Capabilities.Has("other"))
+}
@@ -0,0 +1,254 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+//go:generate go run ./internal/genschema schema/discovery-v1.json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SchemaID identifies the JSON Schema of the discovery format for the current ProtocolVersion. The schema is published
+// at this URL, so tools written in other languages can validate discovery output.
+var SchemaID = fmt.Sprintf(
+	"https://raw.githubusercontent.com/mesosphere/dkp-cli-runtime/main/core/plugin/schema/discovery-v%d.json",
+	ProtocolVersion,
+)
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) used to describe the discovery format.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 jsonType               `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// jsonType is the "type" keyword of a schema. Besides their type, arrays and objects allow null, which is what Go
+// encodes nil slices and maps as, e.g. the values of annotations set without values.
+type jsonType []string
+
+func (t jsonType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t jsonType) allowsNull() bool {
+	return len(t) > 1 && t[1] == "null"
+}
+
+var (
+	discoverySchemaOnce sync.Once
+	discoverySchema     *jsonSchema
+)
+
+func getDiscoverySchema() *jsonSchema {
+	discoverySchemaOnce.Do(func() {
+		defs := map[string]*jsonSchema{}
+		root := schemaForType(reflect.TypeOf(Spec{}), defs)
+		discoverySchema = &jsonSchema{
+			Schema: "https://json-schema.org/draft/2020-12/schema",
+			ID:     SchemaID,
+			Title:  "DKP CLI plugin discovery",
+			Description: fmt.Sprintf("Output of the %q command of DKP CLI plugins, protocol version %d. Properties "+
+				"that are not described are allowed, they are added by newer protocol versions.",
+				DiscoveryCommandName, ProtocolVersion),
+			Ref:  root.Ref,
+			Defs: defs,
+		}
+	})
+	return discoverySchema
+}
+
+// DiscoverySchema returns the JSON Schema of the discovery format (the output of the discovery command, see Spec) for
+// the current ProtocolVersion. It is derived from the Go types, so it always matches them.
+func DiscoverySchema() ([]byte, error) {
+	data, err := json.MarshalIndent(getDiscoverySchema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaForType returns the schema of values of the given type. Structs are added to defs and referenced.
+func schemaForType(t reflect.Type, defs map[string]*jsonSchema) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaForType(t.Elem(), defs)
+	case reflect.String:
+		return &jsonSchema{Type: jsonType{"string"}}
+	case reflect.Bool:
+		return &jsonSchema{Type: jsonType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: jsonType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: jsonType{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: jsonType{"array", "null"}, Items: schemaForType(t.Elem(), defs)}
+	case reflect.Map:
+		return &jsonSchema{Type: jsonType{"object", "null"}, AdditionalProperties: schemaForType(t.Elem(), defs)}
+	case reflect.Struct:
+		ref := &jsonSchema{Ref: "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref
+		}
+		schema := &jsonSchema{Type: jsonType{"object"}, Properties: map[string]*jsonSchema{}}
+		// registered before the fields, so recursive types refer to it
+		defs[t.Name()] = schema
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Properties[name] = schemaForType(field.Type, defs)
+			if !strings.Contains(opts, "omitempty") {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		return ref
+	default:
+		panic(fmt.Sprintf("no JSON schema for type %s", t))
+	}
+}
+
+// SchemaError is returned for documents that don't match the discovery schema.
+type SchemaError struct {
+	// Path is the location of the invalid value, e.g. "commands.sub_commands[0].use".
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateDiscoveryOutput checks that data, the output of a plugin's discovery command, matches the discovery schema
+// (see DiscoverySchema). Plugins not using this runtime can use this to check their implementation. A *SchemaError is
+// returned for the first mismatch.
+func ValidateDiscoveryOutput(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return &SchemaError{Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	schema := getDiscoverySchema()
+	return validateValue(schema, schema, "", document)
+}
+
+func validateValue(root, schema *jsonSchema, path string, value interface{}) error {
+	if schema.Ref != "" {
+		return validateValue(root, root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")], path, value)
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return &SchemaError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+	if len(schema.Type) == 0 || value == nil && schema.Type.allowsNull() {
+		return nil
+	}
+	switch schema.Type[0] {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return invalid("expected string, got %s", jsonTypeOf(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("expected boolean, got %s", jsonTypeOf(value))
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalid("expected integer, got %s", jsonTypeOf(value))
+		}
+		if _, err := number.Int64(); err != nil {
+			return invalid("expected integer, got %s", number)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return invalid("expected number, got %s", jsonTypeOf(value))
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return invalid("expected array, got %s", jsonTypeOf(value))
+		}
+		for i, item := range items {
+			if err := validateValue(root, schema.Items, fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid("expected object, got %s", jsonTypeOf(value))
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return invalid("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				propertySchema = schema.AdditionalProperties
+			}
+			if propertySchema == nil {
+				continue
+			}
+			propertyPath := name
+			if path != "" {
+				propertyPath = path + "." + name
+			}
+			if err := validateValue(root, propertySchema, propertyPath, object[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func TestDiscoverySchemaIsPublished(t *testing.T) {
+	schema, err := plugin.DiscoverySchema()
+	require.NoError(t, err)
+	published, err := os.ReadFile("schema/discovery-v1.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(published), string(schema), "run 'go generate ./plugin' to update the published schema")
+}
+
+func TestValidateDiscoveryOutput(t *testing.T) {
+	pluginCmd := &cobra.Command{Use: "example-plugin"}
+	pluginCmd.PersistentFlags().StringToString("labels", map[string]string{"a": "b"}, "labels")
+	subCmd := &cobra.Command{Use: "action NAME", Args: cobra.ExactArgs(1), Run: func(*cobra.Command, []string) {}}
+	subCmd.Flags().Int("count", 42, "count")
+	_ = subCmd.MarkFlagRequired("count")
+	pluginCmd.AddCommand(subCmd)
+
+	out := bytes.Buffer{}
+	discoveryCmd := plugin.NewDiscoveryCommand(&out, pluginCmd)
+	require.NoError(t, discoveryCmd.Execute())
+	assert.NoError(t, plugin.ValidateDiscoveryOutput(out.Bytes()))
+
+	for document, expected := range map[string]string{
+		`{"commands": {"use": "plugin"}}`:                           "",
+		`{"commands": {"use": "plugin"}, "added_later": [1, 2, 3]}`: "",
+		`{"protocol_version": 1}`:                                   `missing required property "commands"`,
+		`{"protocol_version": 1.5, "commands": {"use": "plugin"}}`:  "protocol_version: expected integer, got 1.5",
+		`{"commands": {"use": "plugin", "sub_commands": [{"use": 1}]}}`: "commands.sub_commands[0].use: " +
+			"expected string, got number",
+		`{"commands": {"use": "plugin", "local_flags": [{"annotations": {"a": "b"}}]}}`: "commands.local_flags[0]" +
+			".annotations.a: expected array, got string",
+		`{"commands": {"use": "plugin", "aliases": null, "annotations": null}}`: "",
+		`{"commands": {"use": null}}`: "commands.use: " +
+			"expected string, got null",
+		`{"commands": {"use": "plugin", "args": {"min": 1}}}`: `commands.args: missing required property "max"`,
+		`[]`: "expected object, got array",
+		`{`:  "invalid JSON: unexpected EOF",
+	} {
+		err := plugin.ValidateDiscoveryOutput([]byte(document))
+		if expected == "" {
+			assert.NoError(t, err, document)
+			continue
+		}
+		schemaErr := &plugin.SchemaError{}
+		require.ErrorAs(t, err, &schemaErr, document)
+		assert.EqualError(t, err, expected, document)
+	}
+}
+
+func TestValidateDiscoveryOutputRoundTrip(t *testing.T) {
+	pluginCmd := &cobra.Command{Use: "example-plugin", Annotations: map[string]string{"a": "b"}}
+	pluginCmd.AddGroup(&cobra.Group{ID: "manage", Title: "Manage"})
+	pluginCmd.PersistentFlags().StringSlice("labels", nil, "labels")
+	subCmd := &cobra.Command{
+		Use:       "action NAME",
+		GroupID:   "manage",
+		Aliases:   []string{"act"},
+		ValidArgs: []string{"a", "b"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Run:       func(*cobra.Command, []string) {},
+	}
+	subCmd.Flags().Int("count", 42, "count")
+	subCmd.Flags().String("format", "", "format")
+	subCmd.Flags().Bool("json", false, "json")
+	// annotations without values are encoded as null
+	require.NoError(t, subCmd.Flags().SetAnnotation("count", "no-values", nil))
+	require.NoError(t, plugin.MarkFlagValues(subCmd, "format", "json", "yaml"))
+	subCmd.MarkFlagsMutuallyExclusive("format", "json")
+	pluginCmd.AddCommand(subCmd)
+
+	out := bytes.Buffer{}
+	discoveryCmd := plugin.NewDiscoveryCommand(&out, pluginCmd)
+	require.NoError(t, discoveryCmd.Execute())
+	require.Contains(t, out.String(), `"no-values":null`)
+	assert.NoError(t, plugin.ValidateDiscoveryOutput(out.Bytes()))
+
+	// the host decodes the same spec
+	spec := plugin.Spec{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &spec))
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+	assert.JSONEq(t, out.String(), string(data))
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+)
+
+func main() {
+	rootCmd := &cobra.Command{Use: "dkp-example"}
+	rootCmd.PersistentFlags().Bool("global", false, "a global flag")
+
+	printArgs := func(cmd *cobra.Command, args []string) {
+		fmt.Print(strings.Trim(fmt.Sprintf("%q", os.Args[1:]), "[]"))
+	}
+
+	greetCmd := &cobra.Command{Use: "greet NAME", Run: printArgs}
+	plugin.SetArgsSpec(greetCmd, plugin.ArgsSpec{Min: 1, Max: 1})
+	greetCmd.Flags().StringSlice("greeting", nil, "greetings")
+	greetCmd.Flags().String("language", "en", "language")
+	greetCmd.Flags().String("template", "", "template file")
+	greetCmd.Flags().String("style", "", "style")
+	_ = plugin.MarkFlagValues(greetCmd, "language", "en", "de")
+	_ = greetCmd.MarkFlagFilename("template", "tmpl")
+	greetCmd.ValidArgsFunction = func(
+		cmd *cobra.Command, args []string, toComplete string,
+	) ([]string, cobra.ShellCompDirective) {
+		language, _ := cmd.Flags().GetString("language")
+		return []string{"alice\tfriend of " + language, "bob"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	_ = greetCmd.RegisterFlagCompletionFunc("style", func(
+		cmd *cobra.Command, args []string, toComplete string,
+	) ([]string, cobra.ShellCompDirective) {
+		return []string{"formal", "casual", strings.Join(args, ",")}, cobra.ShellCompDirectiveNoSpace
+	})
+	rootCmd.AddCommand(greetCmd)
+
+	createCmd := &cobra.Command{Use: "create"}
+	clusterCmd := &cobra.Command{Use: "cluster"}
+	clusterCmd.AddCommand(&cobra.Command{Use: "example", Run: printArgs})
+	createCmd.AddCommand(clusterCmd)
+	rootCmd.AddCommand(createCmd)
+
+	rootCmd.AddGroup(&cobra.Group{ID: "example", Title: "Example Commands:"})
+	rootCmd.AddCommand(&cobra.Command{Use: "fail", GroupID: "example", Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprint(os.Stderr, "failing")
+		os.Exit(3)
+	}})
+
+	rootCmd.AddCommand(&cobra.Command{Use: "events", Run: func(cmd *cobra.Command, args []string) {
+		out := output.NewNonInteractiveShell(os.Stdout, os.Stderr, 0)
+		if fd, err := strconv.Atoi(os.Getenv(plugin.EnvEventStreamFD)); err == nil {
+			out = output.NewEventStreamOutput(os.NewFile(uintptr(fd), "events"), 0)
+		}
+		out.StartOperation("working")
+		out.EndOperationWithStatus(output.Success())
+		out.Result("result")
+	}})
+
+	rootCmd.AddCommand(plugin.NewDiscoveryCommandWithOptions(os.Stdout, rootCmd, &plugin.DiscoveryOptions{
+		Capabilities: plugin.Capabilities{plugin.CapabilityEventStream},
+	}))
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
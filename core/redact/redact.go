@@ -0,0 +1,139 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package redact hides secrets in flags and environment variables before they are persisted or shared, e.g. in the
+// command journal or in support bundles.
+package redact
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// Placeholder replaces redacted values.
+	Placeholder = "<redacted>"
+
+	// SensitiveAnnotation marks a flag whose value must always be redacted, regardless of its name.
+	//
+	// Example:
+	//  _ = cmd.Flags().SetAnnotation("registry-auth", redact.SensitiveAnnotation, []string{"true"})
+	SensitiveAnnotation = "dkp-cli-runtime/sensitive"
+)
+
+// sensitiveWords are words of flag or environment variable names that indicate a secret value. Words consisting of
+// multiple words also match if the words are separated, e.g. "api-key".
+var sensitiveWords = []string{ //nolint:gochecknoglobals // Constant list of words.
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"credential",
+	"apikey",
+	"privatekey",
+	"accesskey",
+	"auth",
+}
+
+// IsSensitive returns true if a flag or environment variable name suggests its value is a secret. Names are matched by
+// words (separated by "-", "_", "." or a change to upper case), so e.g. "registry-password", "GITHUB_TOKEN" and
+// "api-key" are sensitive, but "author" and "oauth-client-id" are not. Plurals like "secrets" are sensitive, too.
+func IsSensitive(name string) bool {
+	words := nameWords(name)
+	for i := range words {
+		// a single word, or up to two words joined
+		for j := i + 1; j <= len(words) && j <= i+2; j++ {
+			word := strings.TrimSuffix(strings.Join(words[i:j], ""), "s")
+			for _, sensitive := range sensitiveWords {
+				if word == sensitive {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// nameWords splits a name into lower case words, e.g. "AWS_SECRET_ACCESS_KEY" and "awsSecretAccessKey" into "aws",
+// "secret", "access" and "key".
+func nameWords(name string) []string {
+	var words []string
+	word := strings.Builder{}
+	endWord := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	previous := ' '
+	for _, r := range name {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+			endWord()
+		case unicode.IsUpper(r) && unicode.IsLower(previous):
+			endWord()
+			word.WriteRune(r)
+		default:
+			word.WriteRune(r)
+		}
+		previous = r
+	}
+	endWord()
+	return words
+}
+
+// Args returns the positional arguments with the values of sensitive "name=value" arguments redacted, e.g. for
+// "--set password=hunter2" passed on to another tool. Other arguments are returned as they are, so commands taking
+// secrets as arguments must not be recorded.
+func Args(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if key, _, found := strings.Cut(arg, "="); found && IsSensitive(key) {
+			arg = key + "=" + Placeholder
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// FlagValue returns the value of the flag, or Placeholder if the flag is sensitive.
+func FlagValue(flag *pflag.Flag) string {
+	if isSensitiveFlag(flag) {
+		return Placeholder
+	}
+	return flag.Value.String()
+}
+
+// ChangedFlags returns the values of all flags explicitly set on the command line, with sensitive values redacted.
+func ChangedFlags(flags *pflag.FlagSet) map[string]string {
+	result := map[string]string{}
+	flags.Visit(func(flag *pflag.Flag) {
+		result[flag.Name] = FlagValue(flag)
+	})
+	return result
+}
+
+// Environ returns the passed environment ("key=value" pairs as returned by os.Environ), sorted by key and with
+// sensitive values redacted.
+func Environ(environ []string) []string {
+	result := make([]string, 0, len(environ))
+	for _, entry := range environ {
+		key, _, found := strings.Cut(entry, "=")
+		if found && IsSensitive(key) {
+			entry = key + "=" + Placeholder
+		}
+		result = append(result, entry)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isSensitiveFlag(flag *pflag.Flag) bool {
+	if values, ok := flag.Annotations[SensitiveAnnotation]; ok {
+		return len(values) == 0 || values[0] != "false"
+	}
+	return IsSensitive(flag.Name)
+}
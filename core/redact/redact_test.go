@@ -0,0 +1,78 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+)
+
+func TestIsSensitive(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"password":              true,
+		"registry-password":     true,
+		"AWS_SECRET_ACCESS_KEY": true,
+		"GITHUB_TOKEN":          true,
+		"api-key":               true,
+		"privateKey":            true,
+		"client-secrets":        true,
+		"basic-auth":            true,
+		"db.password":           true,
+		"kubeconfig":            false,
+		"namespace":             false,
+		"HOME":                  false,
+		"author":                false,
+		"oauth-client-id":       false,
+		"keys":                  false,
+	} {
+		assert.Equal(t, expected, redact.IsSensitive(name), name)
+	}
+}
+
+func TestChangedFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("namespace", "default", "")
+	flags.String("password", "", "")
+	flags.String("registry-mirror", "", "")
+	flags.String("unset", "", "")
+	flags.String("tricky", "", "")
+	flags.String("token-file", "", "")
+	require.NoError(t, flags.SetAnnotation("tricky", redact.SensitiveAnnotation, []string{"true"}))
+	require.NoError(t, flags.SetAnnotation("token-file", redact.SensitiveAnnotation, []string{"false"}))
+
+	require.NoError(t, flags.Parse([]string{
+		"--namespace=kube-system",
+		"--password=hunter2",
+		"--registry-mirror=https://mirror.example.com",
+		"--tricky=value",
+		"--token-file=/tmp/token",
+	}))
+
+	assert.Equal(t, map[string]string{
+		"namespace":       "kube-system",
+		"password":        redact.Placeholder,
+		"registry-mirror": "https://mirror.example.com",
+		"tricky":          redact.Placeholder,
+		"token-file":      "/tmp/token",
+	}, redact.ChangedFlags(flags))
+}
+
+func TestArgs(t *testing.T) {
+	assert.Nil(t, redact.Args(nil))
+	assert.Equal(t, []string{"cluster", "password=" + redact.Placeholder, "replicas=3", "hunter2"},
+		redact.Args([]string{"cluster", "password=hunter2", "replicas=3", "hunter2"}))
+}
+
+func TestEnviron(t *testing.T) {
+	assert.Equal(t, []string{
+		"GITHUB_TOKEN=" + redact.Placeholder,
+		"HOME=/home/user",
+		"NO_VALUE",
+	}, redact.Environ([]string{"HOME=/home/user", "NO_VALUE", "GITHUB_TOKEN=abc"}))
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package retry retries failing functions with exponential backoff, displaying the progress as an Output operation
+// instead of a wall of warnings.
+//
+// Example:
+//
+//	err := retry.Do(ctx, output, retry.Options{
+//		Description: "Waiting for API server",
+//		MaxAttempts: 10,
+//	}, func(ctx context.Context) error {
+//		return pingAPIServer(ctx)
+//	})
+//
+// While retrying, the operation is displayed as "Waiting for API server (attempt 3/10, next in 8s)". The error of
+// each failed attempt is output with verbosity 1.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// Backoff configures the waiting time between attempts. The n-th wait is Initial * Multiplier^(n-1), limited to Max,
+// and randomly varied by +/- Jitter (a fraction of the wait, between 0 and 1).
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff returns a backoff starting at 1s, doubling up to 30s, with 20% jitter.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    time.Second,
+		Max:        30 * time.Second, //nolint:gomnd // Default backoff.
+		Multiplier: 2,                //nolint:gomnd // Default backoff.
+		Jitter:     0.2,              //nolint:gomnd // Default backoff.
+	}
+}
+
+// Wait returns the waiting time after the given (1-based) failed attempt.
+func (b Backoff) Wait(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && wait > float64(b.Max) {
+		wait = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		wait *= 1 + jitter*(2*rand.Float64()-1) //nolint:gosec // No need for a secure random number here.
+	}
+	return time.Duration(wait)
+}
+
+// Options configure how a function is retried.
+type Options struct {
+	// Description is displayed as the operation's status, e.g. "Waiting for API server".
+	Description string
+	// MaxAttempts limits the number of attempts. Unlimited if 0, i.e. until the context is done.
+	MaxAttempts int
+	// Backoff configures the waiting time between attempts. DefaultBackoff is used if not set.
+	Backoff *Backoff
+	// Retryable classifies errors. Errors for which it returns false are not retried. All errors except those
+	// wrapped with Permanent are retried if not set.
+	Retryable func(err error) bool
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so it is not retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent returns true if the error was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
+}
+
+// Error summarises a retried function that did not succeed.
+type Error struct {
+	Description string
+	Attempts    int
+	Elapsed     time.Duration
+	// Last is the error returned by the last attempt, or the context's error if it was done while waiting.
+	Last error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("failed after %s in %s", attemptCount(e.Attempts), output.HumanReadableDuration(e.Elapsed))
+	if e.Description != "" {
+		msg = e.Description + " " + msg
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Last)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Last
+}
+
+// attemptCount returns e.g. "1 attempt" or "3 attempts".
+func attemptCount(attempts int) string {
+	if attempts == 1 {
+		return "1 attempt"
+	}
+	return fmt.Sprintf("%d attempts", attempts)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the maximum number of attempts is reached or the
+// context is done. The attempts are displayed as an operation on out, which ends with the corresponding status. If fn
+// does not succeed, an *Error is returned.
+func Do(ctx context.Context, out output.Output, opts Options, fn func(ctx context.Context) error) error {
+	backoff := DefaultBackoff()
+	if opts.Backoff != nil {
+		backoff = *opts.Backoff
+	}
+	retryable := opts.Retryable
+	if retryable == nil {
+		retryable = func(err error) bool { return true }
+	}
+
+	gauge := &output.ProgressGauge{}
+	gauge.SetStatus(opts.Description)
+	out.StartOperationWithProgress(gauge)
+
+	start := time.Now()
+	fail := func(attempts int, err error) error {
+		gauge.SetStatus(opts.Description)
+		out.EndOperationWithStatus(output.WithReason(endStatus(ctx), attemptCount(attempts)))
+		return &Error{Description: opts.Description, Attempts: attempts, Elapsed: time.Since(start), Last: err}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			gauge.SetStatus(opts.Description)
+			out.EndOperationWithStatus(output.Success())
+			return nil
+		}
+		out.V(1).Infof("%s: attempt %s failed: %v", opts.Description, attemptString(attempt, opts.MaxAttempts), err)
+
+		if IsPermanent(err) || !retryable(err) || ctx.Err() != nil ||
+			(opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts) {
+			return fail(attempt, err)
+		}
+
+		if waitErr := wait(ctx, backoff.Wait(attempt), func(remaining time.Duration) {
+			gauge.SetStatus(fmt.Sprintf("%s (attempt %s, next in %s)",
+				opts.Description, attemptString(attempt+1, opts.MaxAttempts), remaining.Round(time.Second)))
+		}); waitErr != nil {
+			return fail(attempt, err)
+		}
+		gauge.SetStatus(fmt.Sprintf("%s (attempt %s)", opts.Description, attemptString(attempt+1, opts.MaxAttempts)))
+	}
+}
+
+func attemptString(attempt, maxAttempts int) string {
+	if maxAttempts > 0 {
+		return fmt.Sprintf("%d/%d", attempt, maxAttempts)
+	}
+	return fmt.Sprint(attempt)
+}
+
+func endStatus(ctx context.Context) output.EndOperationStatus {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return output.TimedOut()
+	case errors.Is(ctx.Err(), context.Canceled):
+		return output.Cancelled()
+	default:
+		return output.Failure()
+	}
+}
+
+// wait waits for the given duration, calling update with the remaining time every second. It returns the context's
+// error if the context is done before.
+func wait(ctx context.Context, d time.Duration, update func(remaining time.Duration)) error {
+	deadline := time.Now().Add(d)
+	update(d)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			update(time.Until(deadline))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package retry_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/retry"
+)
+
+var fastBackoff = &retry.Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2}
+
+func TestDoSucceeds(t *testing.T) {
+	errOut := bytes.Buffer{}
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 1)
+
+	attempts := 0
+	err := retry.Do(context.Background(), out, retry.Options{
+		Description: "Waiting for API server",
+		MaxAttempts: 5,
+		Backoff:     fastBackoff,
+	}, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	lines := strings.Split(strings.TrimSuffix(errOut.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "INF  • Waiting for API server...")
+	assert.Contains(t, lines[1], "INF Waiting for API server: attempt 1/5 failed: connection refused")
+	assert.Contains(t, lines[2], "INF Waiting for API server: attempt 2/5 failed: connection refused")
+	assert.Contains(t, lines[3], "INF  ✓ Waiting for API server")
+}
+
+func TestDoMaxAttempts(t *testing.T) {
+	errOut := bytes.Buffer{}
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+
+	lastErr := errors.New("connection refused")
+	attempts := 0
+	err := retry.Do(context.Background(), out, retry.Options{
+		Description: "Waiting for API server",
+		MaxAttempts: 3,
+		Backoff:     fastBackoff,
+	}, func(ctx context.Context) error {
+		attempts++
+		return lastErr
+	})
+	assert.Equal(t, 3, attempts)
+
+	retryErr := &retry.Error{}
+	require.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 3, retryErr.Attempts)
+	assert.ErrorIs(t, err, lastErr)
+	assert.Regexp(t, "^Waiting for API server failed after 3 attempts in 00s: connection refused$", err.Error())
+
+	// errors of single attempts are only shown with higher verbosity
+	assert.NotContains(t, errOut.String(), "attempt 1/3 failed")
+	assert.Contains(t, errOut.String(), "ERR  ✗ Waiting for API server (3 attempts)")
+}
+
+func TestDoNotRetryable(t *testing.T) {
+	for name, opts := range map[string]struct {
+		retryable func(error) bool
+		err       error
+	}{
+		"permanent": {err: retry.Permanent(errors.New("unauthorized"))},
+		"classified": {
+			retryable: func(err error) bool { return err.Error() != "unauthorized" },
+			err:       errors.New("unauthorized"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			err := retry.Do(context.Background(), output.NewDiscardingOutput(), retry.Options{
+				Backoff:   fastBackoff,
+				Retryable: opts.retryable,
+			}, func(ctx context.Context) error {
+				attempts++
+				return opts.err
+			})
+			assert.Equal(t, 1, attempts)
+			assert.EqualError(t, err, "failed after 1 attempt in 00s: unauthorized")
+		})
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	errOut := bytes.Buffer{}
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := retry.Do(ctx, out, retry.Options{
+		Description: "Waiting",
+		Backoff:     &retry.Backoff{Initial: time.Hour},
+	}, func(ctx context.Context) error {
+		return errors.New("not ready")
+	})
+	assert.EqualError(t, err, "Waiting failed after 1 attempt in 00s: not ready")
+	assert.Contains(t, errOut.String(), "ERR  ⧗ Waiting (1 attempt)")
+}
+
+func TestBackoffWait(t *testing.T) {
+	backoff := retry.Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+	assert.Equal(t, time.Second, backoff.Wait(1))
+	assert.Equal(t, 2*time.Second, backoff.Wait(2))
+	assert.Equal(t, 4*time.Second, backoff.Wait(3))
+	assert.Equal(t, 5*time.Second, backoff.Wait(4))
+
+	backoff.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		wait := backoff.Wait(2)
+		assert.GreaterOrEqual(t, wait, time.Second)
+		assert.LessOrEqual(t, wait, 3*time.Second)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package supportbundle collects diagnostic information (logs, versions, environment, configuration) into a single
+// tar.gz archive that users can attach to support tickets.
+//
+// Collectors contribute files to the bundle. Downstream CLIs register their own collectors in a Registry:
+//
+//	registry := supportbundle.NewRegistry(supportbundle.DefaultCollectors(rootCmd)...)
+//	registry.Register(supportbundle.NewCollector("cluster", func(ctx context.Context, b *supportbundle.Bundle) error {
+//		return b.AddJSON("cluster.json", clusterInfo)
+//	}))
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bundle is an archive that collectors add files to.
+type Bundle struct {
+	prefix  string
+	tw      *tar.Writer
+	modTime time.Time
+	lock    sync.Mutex
+}
+
+// AddFile adds a file with the given content to the bundle. Directories in the name are created implicitly.
+func (b *Bundle) AddFile(name string, data []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.writeHeader(name, int64(len(data))); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(data)
+	return err
+}
+
+func (b *Bundle) writeHeader(name string, size int64) error {
+	return b.tw.WriteHeader(&tar.Header{
+		Name:    path.Join(b.prefix, name),
+		Mode:    0o600,
+		Size:    size,
+		ModTime: b.modTime,
+	})
+}
+
+// AddJSON adds a file containing the JSON representation of v to the bundle.
+func (b *Bundle) AddJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.AddFile(name, append(data, '\n'))
+}
+
+// AddLocalFile adds a copy of a file on disk to the bundle. The file is streamed into the bundle, so it may be large.
+// If the file grows while it is copied, only the size it had when it was opened is added.
+func (b *Bundle) AddLocalFile(name, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.writeHeader(name, info.Size()); err != nil {
+		return err
+	}
+	_, err = io.CopyN(b.tw, f, info.Size())
+	return err
+}
+
+// Collector contributes files to a support bundle.
+type Collector interface {
+	// Name identifies the collector. Registering a collector with the same name as an existing collector replaces it.
+	Name() string
+	// Collect adds files to the bundle.
+	Collect(ctx context.Context, bundle *Bundle) error
+}
+
+// NewCollector creates a Collector from a function.
+func NewCollector(name string, collect func(ctx context.Context, bundle *Bundle) error) Collector {
+	return &funcCollector{name: name, collect: collect}
+}
+
+type funcCollector struct {
+	name    string
+	collect func(ctx context.Context, bundle *Bundle) error
+}
+
+func (c *funcCollector) Name() string {
+	return c.name
+}
+
+func (c *funcCollector) Collect(ctx context.Context, bundle *Bundle) error {
+	return c.collect(ctx, bundle)
+}
+
+// Registry holds the collectors used to create a support bundle.
+type Registry struct {
+	collectors []Collector
+	lock       sync.RWMutex
+}
+
+// NewRegistry creates a Registry with the given collectors.
+func NewRegistry(collectors ...Collector) *Registry {
+	r := &Registry{}
+	r.Register(collectors...)
+	return r
+}
+
+// Register adds collectors to the registry. A collector replaces a previously registered collector with the same name.
+func (r *Registry) Register(collectors ...Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+outer:
+	for _, collector := range collectors {
+		for i, existing := range r.collectors {
+			if existing.Name() == collector.Name() {
+				r.collectors[i] = collector
+				continue outer
+			}
+		}
+		r.collectors = append(r.collectors, collector)
+	}
+}
+
+// Collectors returns the registered collectors in order of registration.
+func (r *Registry) Collectors() []Collector {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]Collector{}, r.collectors...)
+}
+
+// CollectorError describes a collector that failed.
+type CollectorError struct {
+	Collector string
+	Err       error
+}
+
+func (e CollectorError) Error() string {
+	return fmt.Sprintf("collector %q failed: %v", e.Collector, e.Err)
+}
+
+func (e CollectorError) Unwrap() error {
+	return e.Err
+}
+
+// Write runs all collectors and writes the resulting tar.gz archive to w. All files are placed in a directory named
+// after prefix. A failing collector does not abort the bundle: its error is recorded in the file "errors.txt" inside
+// the bundle and returned together with errors of other collectors. The returned error is only non-nil if the archive
+// could not be written.
+func (r *Registry) Write(ctx context.Context, w io.Writer, prefix string) ([]CollectorError, error) {
+	gw := gzip.NewWriter(w)
+	bundle := &Bundle{
+		prefix:  prefix,
+		tw:      tar.NewWriter(gw),
+		modTime: time.Now(),
+	}
+
+	collectorErrs := []CollectorError{}
+	for _, collector := range r.Collectors() {
+		if err := ctx.Err(); err != nil {
+			return collectorErrs, err
+		}
+		if err := collector.Collect(ctx, bundle); err != nil {
+			collectorErrs = append(collectorErrs, CollectorError{Collector: collector.Name(), Err: err})
+		}
+	}
+
+	if len(collectorErrs) > 0 {
+		lines := make([]string, 0, len(collectorErrs))
+		for _, err := range collectorErrs {
+			lines = append(lines, err.Error())
+		}
+		if err := bundle.AddFile("errors.txt", []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			return collectorErrs, err
+		}
+	}
+
+	if err := bundle.tw.Close(); err != nil {
+		return collectorErrs, err
+	}
+	return collectorErrs, gw.Close()
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supportbundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+	"github.com/mesosphere/dkp-cli-runtime/core/supportbundle"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "dkp"}
+	rootCmd.PersistentFlags().String("kubeconfig", "/kubeconfig", "")
+	rootCmd.PersistentFlags().String("registry-password", "hunter2", "")
+
+	logDir := t.TempDir()
+	for i, name := range []string{"old.log", "newer.log", "newest.log"} {
+		file := filepath.Join(logDir, name)
+		require.NoError(t, os.WriteFile(file, []byte(name), 0o600))
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+
+	registry := supportbundle.NewRegistry(supportbundle.DefaultCollectors(rootCmd)...)
+	registry.Register(
+		supportbundle.LogFilesCollector(filepath.Join(logDir, "*.log"), 2),
+		supportbundle.NewCollector("failing", func(ctx context.Context, bundle *supportbundle.Bundle) error {
+			return errors.New("collector failed")
+		}),
+		supportbundle.NewCollector("environment", func(ctx context.Context, bundle *supportbundle.Bundle) error {
+			return bundle.AddFile("custom-environment.txt", []byte("replaced"))
+		}),
+	)
+
+	buf := bytes.Buffer{}
+	collectorErrs, err := registry.Write(context.Background(), &buf, "bundle")
+	require.NoError(t, err)
+	require.Len(t, collectorErrs, 1)
+	assert.EqualError(t, collectorErrs[0], `collector "failing" failed: collector failed`)
+
+	files := readBundle(t, &buf)
+	assert.ElementsMatch(t, []string{
+		"bundle/version.json",
+		"bundle/custom-environment.txt",
+		"bundle/flags.json",
+		"bundle/system.json",
+		"bundle/plugin-spec.json",
+		"bundle/logs/newest.log",
+		"bundle/logs/newer.log",
+		"bundle/errors.txt",
+	}, keys(files))
+
+	flags := map[string]string{}
+	require.NoError(t, json.Unmarshal(files["bundle/flags.json"], &flags))
+	assert.Equal(t, map[string]string{"kubeconfig": "/kubeconfig", "registry-password": redact.Placeholder}, flags)
+	assert.Equal(t, "newest.log", string(files["bundle/logs/newest.log"]))
+	assert.Equal(t, `collector "failing" failed: collector failed`+"\n", string(files["bundle/errors.txt"]))
+}
+
+func TestEnvironmentCollector(t *testing.T) {
+	t.Setenv("DKP_TEST_TOKEN", "secret")
+	t.Setenv("DKP_TEST_VALUE", "visible")
+
+	registry := supportbundle.NewRegistry(supportbundle.EnvironmentCollector())
+	buf := bytes.Buffer{}
+	_, err := registry.Write(context.Background(), &buf, "bundle")
+	require.NoError(t, err)
+
+	environment := string(readBundle(t, &buf)["bundle/environment.txt"])
+	assert.Contains(t, environment, "DKP_TEST_TOKEN="+redact.Placeholder+"\n")
+	assert.Contains(t, environment, "DKP_TEST_VALUE=visible\n")
+	assert.NotContains(t, environment, "secret")
+}
+
+func TestAddLocalFile(t *testing.T) {
+	localFile := filepath.Join(t.TempDir(), "large.log")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1<<18)
+	require.NoError(t, os.WriteFile(localFile, data, 0o600))
+
+	registry := supportbundle.NewRegistry(
+		supportbundle.NewCollector("local", func(ctx context.Context, bundle *supportbundle.Bundle) error {
+			return bundle.AddLocalFile("large.log", localFile)
+		}),
+	)
+	buf := bytes.Buffer{}
+	collectorErrs, err := registry.Write(context.Background(), &buf, "bundle")
+	require.NoError(t, err)
+	require.Empty(t, collectorErrs)
+
+	assert.Equal(t, data, readBundle(t, &buf)["bundle/large.log"])
+}
+
+func TestCommand(t *testing.T) {
+	// The directory name contains glob metacharacters, which must be matched literally.
+	logDir := filepath.Join(t.TempDir(), "logs[1]")
+	require.NoError(t, os.Mkdir(logDir, 0o700))
+	logFile := filepath.Join(logDir, "dkp.log")
+	for i, name := range []string{logFile + ".2", logFile + ".1", logFile} {
+		require.NoError(t, os.WriteFile(name, []byte(name), 0o600))
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(name, modTime, modTime))
+	}
+
+	rootCmd := &cobra.Command{Use: "dkp"}
+	rootCmd.PersistentFlags().String("log-file", "", "")
+	rootCmd.AddCommand(supportbundle.NewCommand(
+		output.NewDiscardingOutput(),
+		supportbundle.NewRegistry(supportbundle.DefaultCollectors(rootCmd)...),
+	))
+
+	run := func(args ...string) map[string][]byte {
+		outputFile := filepath.Join(t.TempDir(), "bundle.tar.gz")
+		rootCmd.SetArgs(append([]string{"support-bundle", "--output-file", outputFile}, args...))
+		require.NoError(t, rootCmd.Execute())
+
+		f, err := os.Open(outputFile)
+		require.NoError(t, err)
+		defer f.Close()
+		return readBundle(t, f)
+	}
+
+	assert.Len(t, run(), 5)
+
+	assert.ElementsMatch(t, []string{"dkp.log", "dkp.log.1", "dkp.log.2"}, logNames(run("--log-file", logFile)))
+	assert.Equal(t, []string{"dkp.log"}, logNames(run("--log-file", logFile, "--logs", "1")))
+	assert.Empty(t, logNames(run("--log-file", logFile, "--logs", "0")))
+}
+
+// logNames returns the names of the log files in the bundle.
+func logNames(files map[string][]byte) []string {
+	names := []string{}
+	for key := range files {
+		if _, name, ok := strings.Cut(key, "/logs/"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func readBundle(t *testing.T, r io.Reader) map[string][]byte {
+	t.Helper()
+	gr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = data
+	}
+	return files
+}
+
+func keys(m map[string][]byte) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supportbundle
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/cmd/version"
+	"github.com/mesosphere/dkp-cli-runtime/core/journal"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
+	"github.com/mesosphere/dkp-cli-runtime/core/redact"
+	"github.com/mesosphere/dkp-cli-runtime/core/term"
+)
+
+// DefaultLogFiles is the number of the latest log files added to a support bundle by default.
+const DefaultLogFiles = 5
+
+// DefaultCollectors returns the collectors every support bundle should contain: versions, environment, flags,
+// system information, the command tree and the latest log files written with the root command's "--log-file" flag.
+func DefaultCollectors(rootCmd *cobra.Command) []Collector {
+	return []Collector{
+		logFileFlagCollector(rootCmd, DefaultLogFiles),
+		VersionCollector(func() (version.Versions, error) {
+			return version.Versions{"": version.GetVersion()}, nil
+		}),
+		EnvironmentCollector(),
+		FlagsCollector(rootCmd),
+		SystemCollector(),
+		PluginSpecCollector(rootCmd),
+	}
+}
+
+// VersionCollector adds the versions of all components to the bundle (file "version.json"). Use the same version
+// getter as passed to version.NewCommandWithVersionGetter.
+func VersionCollector(getVersions func() (version.Versions, error)) Collector {
+	return NewCollector("version", func(ctx context.Context, bundle *Bundle) error {
+		versions, err := getVersions()
+		if err != nil {
+			return err
+		}
+		return bundle.AddJSON("version.json", versions)
+	})
+}
+
+// EnvironmentCollector adds the environment variables to the bundle (file "environment.txt"). Sensitive values are
+// redacted.
+func EnvironmentCollector() Collector {
+	return NewCollector("environment", func(ctx context.Context, bundle *Bundle) error {
+		return bundle.AddFile("environment.txt", []byte(strings.Join(redact.Environ(os.Environ()), "\n")+"\n"))
+	})
+}
+
+// FlagsCollector adds the effective values of all persistent flags of the root command to the bundle (file
+// "flags.json"). Sensitive values are redacted.
+func FlagsCollector(rootCmd *cobra.Command) Collector {
+	return NewCollector("flags", func(ctx context.Context, bundle *Bundle) error {
+		flags := map[string]string{}
+		rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			flags[flag.Name] = redact.FlagValue(flag)
+		})
+		return bundle.AddJSON("flags.json", flags)
+	})
+}
+
+type systemInfo struct {
+	OS        string       `json:"os"`
+	Arch      string       `json:"arch"`
+	GoVersion string       `json:"goVersion"`
+	NumCPU    int          `json:"numCPU"`
+	Terminal  terminalInfo `json:"terminal"`
+}
+
+type terminalInfo struct {
+	StdoutIsTerminal      bool   `json:"stdoutIsTerminal"`
+	StderrIsTerminal      bool   `json:"stderrIsTerminal"`
+	StderrIsSmartTerminal bool   `json:"stderrIsSmartTerminal"`
+	Term                  string `json:"term,omitempty"`
+	ColorTerm             string `json:"colorTerm,omitempty"`
+	NoColor               bool   `json:"noColor"`
+}
+
+// SystemCollector adds information about the operating system and terminal capabilities to the bundle (file
+// "system.json").
+func SystemCollector() Collector {
+	return NewCollector("system", func(ctx context.Context, bundle *Bundle) error {
+		_, noColor := os.LookupEnv("NO_COLOR")
+		return bundle.AddJSON("system.json", systemInfo{
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			GoVersion: runtime.Version(),
+			NumCPU:    runtime.NumCPU(),
+			Terminal: terminalInfo{
+				StdoutIsTerminal:      term.IsTerminal(os.Stdout),
+				StderrIsTerminal:      term.IsTerminal(os.Stderr),
+				StderrIsSmartTerminal: term.IsSmartTerminal(os.Stderr),
+				Term:                  os.Getenv("TERM"),
+				ColorTerm:             os.Getenv("COLORTERM"),
+				NoColor:               noColor,
+			},
+		})
+	})
+}
+
+// PluginSpecCollector adds the plugin discovery spec of the command tree to the bundle (file "plugin-spec.json").
+func PluginSpecCollector(rootCmd *cobra.Command) Collector {
+	return NewCollector("plugin-spec", func(ctx context.Context, bundle *Bundle) error {
+		return bundle.AddJSON("plugin-spec.json", plugin.NewSpec(rootCmd, plugin.DiscoveryOptions{}))
+	})
+}
+
+// JournalCollector adds the latest entries of the command journal to the bundle (file "journal.json").
+func JournalCollector(j *journal.Journal, limit int) Collector {
+	return NewCollector("journal", func(ctx context.Context, bundle *Bundle) error {
+		entries, err := j.Entries()
+		if err != nil {
+			return err
+		}
+		return bundle.AddJSON("journal.json", journal.Filter{Limit: limit}.Apply(entries))
+	})
+}
+
+// LogFilesCollector adds the latest n files matching the glob pattern (see filepath.Glob) to the bundle, in the
+// directory "logs". Files are ordered by modification time. The "--logs" flag of the support bundle command overrides
+// n.
+func LogFilesCollector(pattern string, n int) Collector {
+	return NewCollector("logs", func(ctx context.Context, bundle *Bundle) error {
+		return collectLogFiles(ctx, bundle, n, func() ([]string, error) {
+			return filepath.Glob(pattern)
+		})
+	})
+}
+
+// logFileFlagCollector adds the latest n log files written with the root command's "--log-file" flag, including
+// rotated files with the same prefix. It is replaced by LogFilesCollector.
+func logFileFlagCollector(rootCmd *cobra.Command, n int) Collector {
+	return NewCollector("logs", func(ctx context.Context, bundle *Bundle) error {
+		logFile, _ := rootCmd.PersistentFlags().GetString("log-file")
+		if logFile == "" {
+			return nil
+		}
+		return collectLogFiles(ctx, bundle, n, func() ([]string, error) {
+			return filesWithPrefix(logFile)
+		})
+	})
+}
+
+// filesWithPrefix returns the paths of the entries in the directory of prefix whose names start with the base name of
+// prefix. Unlike a glob pattern, the prefix is matched literally.
+func filesWithPrefix(prefix string) ([]string, error) {
+	dir, base := filepath.Split(prefix)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var matches []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), base) {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches, nil
+}
+
+type logFilesKey struct{}
+
+// withLogFiles overrides the number of log files added to a bundle by the collectors.
+func withLogFiles(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, logFilesKey{}, n)
+}
+
+// collectLogFiles adds the latest n of the files returned by find to the bundle.
+func collectLogFiles(ctx context.Context, bundle *Bundle, n int, find func() ([]string, error)) error {
+	if override, ok := ctx.Value(logFilesKey{}).(int); ok {
+		if override <= 0 {
+			return nil
+		}
+		n = override
+	}
+
+	matches, err := find()
+	if err != nil {
+		return err
+	}
+
+	type logFile struct {
+		path    string
+		modTime int64
+	}
+	files := make([]logFile, 0, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			continue
+		}
+		files = append(files, logFile{path: match, modTime: info.ModTime().UnixNano()})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime > files[j].modTime
+	})
+	if n > 0 && len(files) > n {
+		files = files[:n]
+	}
+
+	for _, file := range files {
+		if err := bundle.AddLocalFile(path.Join("logs", filepath.Base(file.path)), file.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supportbundle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// NewCommand returns a cobra command collecting a support bundle using the registered collectors.
+func NewCommand(out output.Output, registry *Registry) *cobra.Command {
+	outputFile := ""
+	logFiles := DefaultLogFiles
+
+	cmd := &cobra.Command{
+		Use:     "support-bundle",
+		Aliases: []string{"diagnose"},
+		Short:   "Collect diagnostic information into an archive",
+		Long: `Collect logs, version information, environment and configuration into a tar.gz archive.
+Sensitive values are redacted. Attach the archive when reporting an issue.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := strings.ReplaceAll(cmd.Root().Name(), " ", "_") + "-support-bundle-" +
+				time.Now().Format("20060102-150405")
+			file := outputFile
+			if file == "" {
+				file = name + ".tar.gz"
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if cmd.Flags().Changed("logs") {
+				ctx = withLogFiles(ctx, logFiles)
+			}
+
+			out.StartOperation("Collecting support bundle")
+			f, err := os.Create(file)
+			if err != nil {
+				out.EndOperationWithStatus(output.Failure())
+				return err
+			}
+			collectorErrs, err := registry.Write(ctx, f, name)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				out.EndOperationWithStatus(output.Failure())
+				return fmt.Errorf("failed to write support bundle: %w", err)
+			}
+			out.EndOperationWithStatus(output.Success())
+
+			for _, collectorErr := range collectorErrs {
+				out.Warn(collectorErr.Error())
+			}
+			out.Infof("Support bundle written to %s", file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&outputFile, "output-file", "f", outputFile,
+		"File to write the support bundle to. Defaults to <command>-support-bundle-<timestamp>.tar.gz")
+	cmd.Flags().IntVar(&logFiles, "logs", logFiles, "Number of the latest log files to include, 0 for none")
+	return cmd
+}
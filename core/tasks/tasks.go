@@ -0,0 +1,300 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tasks runs named tasks in parallel with bounded concurrency, optionally ordered by dependencies between them,
+// and displays their progress as a single Output operation.
+//
+// Example:
+//
+//	results, err := tasks.Run(ctx, output, tasks.Options{
+//		Description: "Installing packages",
+//		Concurrency: 4,
+//	}, tasks.Task{
+//		Name: "cert-manager",
+//		Run:  installCertManager,
+//	}, tasks.Task{
+//		Name:      "traefik",
+//		DependsOn: []string{"cert-manager"},
+//		Run:       installTraefik,
+//	})
+//
+// While running, the operation is displayed as a progress bar of finished tasks. A line is printed for each finished
+// task, and a summary table of all tasks at the end.
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/internal/dag"
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// Status is the outcome of a task.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusSkipped is the status of tasks whose dependencies did not succeed.
+	StatusSkipped Status = "skipped"
+	// StatusCancelled is the status of tasks that did not run because the context was done, e.g. after another
+	// task failed with Options.FailFast.
+	StatusCancelled Status = "cancelled"
+)
+
+// Task describes a single unit of work.
+type Task struct {
+	// Name uniquely identifies the task, e.g. for dependencies.
+	Name string
+	// Description is displayed when the task is finished. Defaults to Name.
+	Description string
+	// DependsOn lists names of tasks that have to succeed before this task runs. Otherwise this task is skipped.
+	DependsOn []string
+	// Run executes the task.
+	Run func(ctx context.Context) error
+}
+
+// Options configure how tasks are run.
+type Options struct {
+	// Description is displayed as the operation's status, e.g. "Installing packages".
+	Description string
+	// Concurrency limits the number of tasks running at the same time. Unlimited if 0.
+	Concurrency int
+	// FailFast cancels all tasks that did not start yet once a task failed.
+	FailFast bool
+	// HideSummary disables the summary table printed after all tasks finished.
+	HideSummary bool
+}
+
+// Result is the outcome of a single task.
+type Result struct {
+	Name        string
+	Description string
+	Status      Status
+	// Err is the error returned by the task, or the reason it was skipped or cancelled.
+	Err      error
+	Duration time.Duration
+}
+
+// Results contains the results of all tasks, in the order they were passed to Run.
+type Results []Result
+
+// Failed returns the results of all tasks that did not succeed.
+func (r Results) Failed() Results {
+	failed := Results{}
+	for _, result := range r {
+		if result.Status != StatusSucceeded {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Count returns the number of tasks with the given status.
+func (r Results) Count(status Status) int {
+	count := 0
+	for _, result := range r {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteSummary writes a table of all tasks with their status, duration and error to w.
+func (r Results) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:gomnd // Column padding.
+	fmt.Fprintln(tw, "TASK\tSTATUS\tDURATION\tERROR")
+	for _, result := range r {
+		errMsg := ""
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			result.Name, result.Status, output.HumanReadableDuration(result.Duration), errMsg)
+	}
+	return tw.Flush()
+}
+
+// Error is returned by Run if any task did not succeed.
+type Error struct {
+	// Failed contains the results of all tasks that did not succeed.
+	Failed Results
+	Total  int
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", result.Name, result.Err))
+	}
+	return fmt.Sprintf("%d of %d tasks did not succeed: %s", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
+
+// Run runs the tasks concurrently, respecting dependencies, and displays their progress as an operation on out. An
+// *Error is returned if any task did not succeed. Other errors are returned if the tasks are invalid, e.g. because of
+// unknown or cyclic dependencies; no task is run in that case.
+func Run(ctx context.Context, out output.Output, opts Options, tasks ...Task) (Results, error) {
+	tasks = append([]Task{}, tasks...)
+	if err := validate(tasks); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	semaphore := dag.NewSemaphore(opts.Concurrency)
+	results := make(Results, len(tasks))
+
+	gauge := &output.ProgressGauge{}
+	gauge.SetStatus(opts.Description)
+	gauge.SetCapacity(len(tasks))
+	gauge.InitStartTime()
+	out.StartOperationWithProgress(gauge)
+
+	// finished tasks are reported one at a time, so their lines are not interleaved
+	var reportLock sync.Mutex
+	report := func(result Result) {
+		reportLock.Lock()
+		defer reportLock.Unlock()
+		gauge.Inc()
+		if result.Status == StatusFailed {
+			out.Error(result.Err, statusLine(result.Status, result.Description))
+			return
+		}
+		msg := result.Description
+		if result.Status != StatusSucceeded && result.Err != nil {
+			msg = fmt.Sprintf("%s: %v", msg, result.Err)
+		}
+		if result.Status == StatusCancelled {
+			out.Warn(statusLine(result.Status, msg))
+		} else {
+			out.Info(statusLine(result.Status, msg))
+		}
+	}
+
+	dag.Start(nodes(tasks), func(i int, wait func(dependency string) int) {
+		results[i] = runTask(ctx, tasks[i], semaphore, func(dependency string) *Result {
+			return &results[wait(dependency)]
+		})
+		if results[i].Status == StatusFailed && opts.FailFast {
+			cancel()
+		}
+		report(results[i])
+	}).WaitAll()
+
+	failed := results.Failed()
+	if len(failed) == 0 {
+		out.EndOperationWithStatus(output.Success())
+	} else {
+		reason := fmt.Sprintf("%d of %d failed", len(failed), len(tasks))
+		out.EndOperationWithStatus(output.WithReason(output.Failure(), reason))
+	}
+
+	if !opts.HideSummary {
+		summary := &bytes.Buffer{}
+		_ = results.WriteSummary(summary)
+		// line by line, so each line of the table is logged as a message of its own
+		for _, line := range strings.Split(strings.TrimSuffix(summary.String(), "\n"), "\n") {
+			out.Info(line)
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &Error{Failed: failed, Total: len(tasks)}
+	}
+	return results, nil
+}
+
+func runTask(ctx context.Context, task Task, semaphore dag.Semaphore, waitFor func(dependency string) *Result) Result {
+	for _, dependency := range task.DependsOn {
+		if result := waitFor(dependency); result.Status != StatusSucceeded {
+			return newResult(task, StatusSkipped, fmt.Errorf("dependency %q %s", dependency, result.Status), 0)
+		}
+	}
+
+	release, err := semaphore.Acquire(ctx)
+	if err != nil {
+		return newResult(task, StatusCancelled, err, 0)
+	}
+	defer release()
+	if err := ctx.Err(); err != nil {
+		return newResult(task, StatusCancelled, err, 0)
+	}
+
+	start := time.Now()
+	if err := task.Run(ctx); err != nil {
+		return newResult(task, StatusFailed, err, time.Since(start))
+	}
+	return newResult(task, StatusSucceeded, nil, time.Since(start))
+}
+
+func newResult(task Task, status Status, err error, duration time.Duration) Result {
+	return Result{
+		Name:        task.Name,
+		Description: task.Description,
+		Status:      status,
+		Err:         err,
+		Duration:    duration,
+	}
+}
+
+// statusLine formats the line reporting a finished task, e.g. " ✓ cert-manager". Like the end status of operations,
+// failed tasks are reported as errors and cancelled tasks as warnings.
+func statusLine(status Status, msg string) string {
+	line := &strings.Builder{}
+	_, _ = endOperationStatus(status).Fprintln(line, "%s", msg)
+	return strings.TrimSuffix(line.String(), "\n")
+}
+
+func endOperationStatus(status Status) output.EndOperationStatus {
+	switch status {
+	case StatusSucceeded:
+		return output.Success()
+	case StatusSkipped:
+		return output.Skipped()
+	case StatusCancelled:
+		return output.Cancelled()
+	default:
+		return output.Failure()
+	}
+}
+
+// validate makes sure task names are unique, all tasks can be run, all dependencies exist and there are no cycles. It
+// defaults empty descriptions to the task name.
+func validate(tasks []Task) error {
+	byName := make(map[string]Task, len(tasks))
+	for i, task := range tasks {
+		if task.Name == "" {
+			return errors.New("task name must not be empty")
+		}
+		if task.Run == nil {
+			return fmt.Errorf("task %q has no run function", task.Name)
+		}
+		if _, ok := byName[task.Name]; ok {
+			return fmt.Errorf("task %q is defined more than once", task.Name)
+		}
+		if task.Description == "" {
+			tasks[i].Description = task.Name
+		}
+		byName[task.Name] = task
+	}
+
+	return dag.Validate("task", nodes(tasks))
+}
+
+func nodes(tasks []Task) []dag.Node {
+	nodes := make([]dag.Node, 0, len(tasks))
+	for _, task := range tasks {
+		nodes = append(nodes, dag.Node{Name: task.Name, DependsOn: task.DependsOn})
+	}
+	return nodes
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tasks_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/tasks"
+)
+
+func succeed(ctx context.Context) error { return nil }
+
+func fail(ctx context.Context) error { return errors.New("install failed") }
+
+func TestRun(t *testing.T) {
+	errOut := bytes.Buffer{}
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &errOut, 0)
+
+	results, err := tasks.Run(context.Background(), out, tasks.Options{Description: "Installing packages"},
+		tasks.Task{Name: "cert-manager", Run: succeed},
+		tasks.Task{Name: "traefik", DependsOn: []string{"cert-manager"}, Run: fail},
+		tasks.Task{Name: "dashboard", Description: "Installing dashboard", DependsOn: []string{"traefik"}, Run: succeed},
+		tasks.Task{Name: "logging", Run: succeed},
+	)
+
+	tasksErr := &tasks.Error{}
+	require.ErrorAs(t, err, &tasksErr)
+	assert.EqualError(t, err,
+		`2 of 4 tasks did not succeed: traefik: install failed; dashboard: dependency "traefik" failed`)
+
+	statuses := map[string]tasks.Status{}
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+	}
+	assert.Equal(t, map[string]tasks.Status{
+		"cert-manager": tasks.StatusSucceeded,
+		"traefik":      tasks.StatusFailed,
+		"dashboard":    tasks.StatusSkipped,
+		"logging":      tasks.StatusSucceeded,
+	}, statuses)
+	assert.Equal(t, 2, results.Count(tasks.StatusSucceeded))
+
+	assert.Contains(t, errOut.String(), "INF  • Installing packages [")
+	assert.Contains(t, errOut.String(), "INF  ✓ cert-manager")
+	assert.Regexp(t, `ERR  ✗ traefik +err="install failed"\n`, errOut.String())
+	assert.Contains(t, errOut.String(), `INF  ∅ Installing dashboard: dependency "traefik" failed`)
+	assert.Regexp(t, `ERR  ✗ Installing packages .*\(2 of 4 failed\)\n`, errOut.String())
+	assert.Regexp(t, `INF TASK +STATUS +DURATION +ERROR\n`, errOut.String())
+	assert.Regexp(t, `INF traefik +failed +00s +install failed\n`, errOut.String())
+}
+
+func TestRunDependencyOrder(t *testing.T) {
+	var lock sync.Mutex
+	order := []string{}
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	_, err := tasks.Run(context.Background(), output.NewDiscardingOutput(), tasks.Options{HideSummary: true},
+		tasks.Task{Name: "c", DependsOn: []string{"b"}, Run: record("c")},
+		tasks.Task{Name: "b", DependsOn: []string{"a"}, Run: record("b")},
+		tasks.Task{Name: "a", Run: record("a")},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestRunConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	task := func(ctx context.Context) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if current <= old || atomic.CompareAndSwapInt32(&maxRunning, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	list := []tasks.Task{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		list = append(list, tasks.Task{Name: name, Run: task})
+	}
+	_, err := tasks.Run(context.Background(), output.NewDiscardingOutput(), tasks.Options{Concurrency: 2}, list...)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestRunFailFast(t *testing.T) {
+	results, err := tasks.Run(context.Background(), output.NewDiscardingOutput(),
+		tasks.Options{Concurrency: 1, FailFast: true},
+		tasks.Task{Name: "a", Run: fail},
+		tasks.Task{Name: "b", DependsOn: []string{"a"}, Run: succeed},
+		tasks.Task{Name: "c", DependsOn: []string{"a"}, Run: succeed},
+		tasks.Task{Name: "d", Run: func(ctx context.Context) error {
+			// make sure "a" ran first
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}},
+	)
+	require.Error(t, err)
+	assert.Equal(t, tasks.StatusFailed, results[0].Status)
+	assert.Equal(t, tasks.StatusSkipped, results[1].Status)
+	assert.Equal(t, tasks.StatusSkipped, results[2].Status)
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := tasks.Run(ctx, output.NewDiscardingOutput(), tasks.Options{},
+		tasks.Task{Name: "a", Run: succeed},
+	)
+	assert.EqualError(t, err, "1 of 1 tasks did not succeed: a: context canceled")
+	assert.Equal(t, tasks.StatusCancelled, results[0].Status)
+}
+
+func TestInvalidTasks(t *testing.T) {
+	for _, test := range []struct {
+		tasks []tasks.Task
+		err   string
+	}{
+		{[]tasks.Task{{Run: succeed}}, "task name must not be empty"},
+		{[]tasks.Task{{Name: "a"}}, `task "a" has no run function`},
+		{[]tasks.Task{{Name: "a", Run: succeed}, {Name: "a", Run: succeed}}, `task "a" is defined more than once`},
+		{
+			[]tasks.Task{{Name: "a", DependsOn: []string{"unknown"}, Run: succeed}},
+			`task "a" depends on unknown task "unknown"`,
+		},
+		{
+			[]tasks.Task{
+				{Name: "a", DependsOn: []string{"b"}, Run: succeed},
+				{Name: "b", DependsOn: []string{"a"}, Run: succeed},
+			},
+			"cyclic task dependencies: a -> b -> a",
+		},
+	} {
+		_, err := tasks.Run(context.Background(), output.NewDiscardingOutput(), tasks.Options{}, test.tasks...)
+		assert.EqualError(t, err, test.err)
+	}
+}
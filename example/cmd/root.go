@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"errors"
 	"io"
 	"os"
 
@@ -11,12 +12,12 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"github.com/mesosphere/dkp-cli-runtime/core/cmd/root"
-	"github.com/mesosphere/dkp-cli-runtime/core/output"
+	"github.com/mesosphere/dkp-cli-runtime/core/plugin"
 	"github.com/mesosphere/dkp-cli-runtime/extensions/cmd/get"
 	"github.com/mesosphere/dkp-cli-runtime/extensions/options"
 )
 
-func NewCommand(in io.Reader, out, errOut io.Writer) (*cobra.Command, output.Output) {
+func NewCommand(in io.Reader, out, errOut io.Writer) (*cobra.Command, *root.RootOptions) {
 	rootCmd, rootOpts := root.NewCommand(out, errOut)
 
 	clientOpts := options.NewClientOptions(true)
@@ -25,15 +26,25 @@ func NewCommand(in io.Reader, out, errOut io.Writer) (*cobra.Command, output.Out
 	ioStreams := genericclioptions.IOStreams{In: in, Out: out, ErrOut: errOut}
 	rootCmd.AddCommand(get.NewCommand(ioStreams, clientOpts, "pods"))
 
-	return rootCmd, rootOpts.Output
+	return rootCmd, rootOpts
 }
 
 func Execute() {
-	rootCmd, out := NewCommand(os.Stdin, os.Stdout, os.Stderr)
+	rootCmd, rootOpts := NewCommand(os.Stdin, os.Stdout, os.Stderr)
 	rootCmd.SilenceErrors = true
 
-	if err := rootCmd.Execute(); err != nil {
-		out.Error(err, "")
+	err := rootCmd.Execute()
+	var exitErr *plugin.ExitError
+	// the plugin has already reported its error
+	if err != nil && !errors.As(err, &exitErr) {
+		rootOpts.Output.Error(err, "")
+	}
+	rootOpts.Close() //nolint:errcheck // nothing left to report the error to
+
+	switch {
+	case exitErr != nil:
+		os.Exit(exitErr.ExitCode())
+	case err != nil:
 		os.Exit(1)
 	}
 }
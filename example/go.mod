@@ -13,7 +13,7 @@ replace (
 require (
 	github.com/mesosphere/dkp-cli-runtime/core v0.5.2
 	github.com/mesosphere/dkp-cli-runtime/extensions v0.5.2
-	github.com/spf13/cobra v1.5.0
+	github.com/spf13/cobra v1.6.1
 	k8s.io/cli-runtime v0.25.0
 )
 
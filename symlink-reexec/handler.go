@@ -4,64 +4,159 @@
 package symlinkexechandler
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
 )
 
-// HandleExec translates the command invoked into any necessary subcommands, re-execing
-// the target executable as necessary, and returns true if the executable is re-execed, false
-// otherwise.
+// EnvReexeced is set in the environment of the re-execed process to the name it is invoked with. A process invoked
+// with this name is not re-execed again, so misconfigured aliases cannot cause an endless loop of re-execs.
+const EnvReexeced = "SYMLINK_REEXEC_NAME"
+
+// Options configure how the invoked name is translated into subcommands, see HandleExecWithOptions.
+type Options struct {
+	// Prefix is stripped from the invoked name before the rest is translated into subcommands by splitting on "-" and
+	// replacing "_" with "-" in each subcommand. Names without this prefix are not translated.
+	Prefix string
+	// Name is used as the name of the re-execed process. Defaults to Prefix without trailing "-".
+	Name string
+	// Aliases map invoked names to the arguments passed instead, e.g. "kubectl-dkp" to {"kubectl"}. Aliases take
+	// precedence over the translation of prefixed names, so they can express subcommands the translation can't.
+	Aliases map[string][]string
+}
+
+func (o Options) prefix() string {
+	if o.Prefix == "" || strings.HasSuffix(o.Prefix, "-") {
+		return o.Prefix
+	}
+	return o.Prefix + "-"
+}
+
+func (o Options) name() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return strings.TrimSuffix(o.Prefix, "-")
+}
+
+// Resolve translates the name the executable is invoked with (argv0) into subcommands and returns the arguments to
+// re-exec the executable with, starting with the name of the process, and true. It returns false if argv0 doesn't
+// need to be translated. Resolve has no side effects.
+func (o Options) Resolve(argv0 string, args []string) ([]string, bool) {
+	executableInvoked := filepath.Base(argv0)
+
+	var subcommands []string
+	if alias, ok := o.Aliases[executableInvoked]; ok {
+		subcommands = alias
+	} else {
+		prefix := o.prefix()
+		// If the executable invoked doesn't start with the specified prefix, then this is just a
+		// standard symlink - nothing to translate.
+		if prefix == "" || !strings.HasPrefix(executableInvoked, prefix) {
+			return nil, false
+		}
+		executableInvoked = strings.TrimPrefix(executableInvoked, prefix)
+
+		// Split on "-" and replace "_" with "-" for each subcommand.
+		for _, s := range strings.Split(executableInvoked, "-") {
+			subcommands = append(subcommands, strings.ReplaceAll(s, "_", "-"))
+		}
+	}
+
+	resolved := make([]string, 0, 1+len(subcommands)+len(args))
+	resolved = append(resolved, o.name())
+	resolved = append(resolved, subcommands...)
+	// Append all the other args specified too.
+	return append(resolved, args...), true
+}
+
+// HandleExecWithOptions translates the command invoked into any necessary subcommands (see Options.Resolve), re-execing
+// the target executable as necessary. It returns true if the executable is re-execed, false otherwise, and an error if
+// the executable cannot be re-execed.
 //
 // Idiomatic use is as first lines in your main:
 //
-//	if symlinkexechandler.HandleExec("simple") {
+//	reexeced, err := symlinkexechandler.HandleExecWithOptions(symlinkexechandler.Options{Prefix: "simple"})
+//	if err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(1)
+//	}
+//	if reexeced {
 //		return
 //	}
-func HandleExec(prefixToStrip string) bool {
-	if !strings.HasSuffix(prefixToStrip, "-") {
-		prefixToStrip += "-"
+func HandleExecWithOptions(opts Options) (bool, error) {
+	// A process re-execed by this function is not re-execed again.
+	if name, ok := os.LookupEnv(EnvReexeced); ok {
+		_ = os.Unsetenv(EnvReexeced)
+		if name == filepath.Base(os.Args[0]) {
+			return false, nil
+		}
 	}
-	reexecedName := strings.TrimSuffix(prefixToStrip, "-")
-
-	// Store the name used to invoke this process.
-	executableInvoked := filepath.Base(os.Args[0])
 
-	// If the executable invoked doesn't start with the specified prefix, then this is just a
-	// standard symlink - return here rather than re-execing.
-	if !strings.HasPrefix(executableInvoked, prefixToStrip) {
-		return false
-	}
-	executableInvoked = strings.TrimPrefix(executableInvoked, prefixToStrip)
-
-	// Construct the args to pass to the re-execed executable splitting on "-" and replacing
-	// "_" with "-" for each subcommand.
-	executableInvokedSplit := strings.Split(executableInvoked, "-")
-	args := make([]string, 0, len(executableInvokedSplit)+len(os.Args[1:]))
-	for _, s := range executableInvokedSplit {
-		args = append(args, strings.ReplaceAll(s, "_", "-"))
+	args, ok := opts.Resolve(os.Args[0], os.Args[1:])
+	if !ok {
+		return false, nil
 	}
-	// Append all the other args specified too.
-	args = append(args, os.Args[1:]...)
 
 	// Get the actual executable used to start this process.
 	executable, err := os.Executable()
 	if err != nil {
-		panic(err)
+		return false, fmt.Errorf("failed to determine executable: %w", err)
 	}
 
 	// Executable could be a symlink, so resolve to the actual file if necessary.
 	executable, err = filepath.EvalSymlinks(executable)
 	if err != nil {
-		panic(err)
+		return false, fmt.Errorf("failed to resolve executable: %w", err)
 	}
 
 	// Re-exec using the actual executable, passing subcommands derived from
-	// the name of the executable ivoked and flags/arguments passed to the original invocation.
-	if err := syscall.Exec(executable, append([]string{reexecedName}, args...), os.Environ()); err != nil {
-		panic(err)
+	// the name of the executable invoked and flags/arguments passed to the original invocation.
+	env := append(os.Environ(), EnvReexeced+"="+filepath.Base(args[0]))
+	if err := syscall.Exec(executable, args, env); err != nil {
+		return false, fmt.Errorf("failed to re-exec %s: %w", executable, err)
 	}
 
+	return true, nil
+}
+
+// HandleInProcess translates the command invoked into any necessary subcommands (see Options.Resolve) like
+// HandleExecWithOptions, but rewrites os.Args instead of re-execing the executable, busybox-style. It returns true if
+// os.Args is rewritten. This avoids the cost of starting another process and works where exec is not available or
+// undesirable.
+//
+// Idiomatic use is as first lines in your main, before the root command is created:
+//
+//	symlinkexechandler.HandleInProcess(symlinkexechandler.Options{Prefix: "simple"})
+//	rootCmd, rootOptions := root.NewCommand(os.Stdout, os.Stderr)
+func HandleInProcess(opts Options) bool {
+	args, ok := opts.Resolve(os.Args[0], os.Args[1:])
+	if !ok {
+		return false
+	}
+	os.Args = args
 	return true
 }
+
+// HandleExec translates the command invoked into any necessary subcommands, re-execing
+// the target executable as necessary, and returns true if the executable is re-execed, false
+// otherwise. It panics if the executable cannot be re-execed, use HandleExecWithOptions to
+// handle errors or configure the translation.
+//
+// Idiomatic use is as first lines in your main:
+//
+//	if symlinkexechandler.HandleExec("simple") {
+//		return
+//	}
+//
+// The symlinks for the commands of a cobra command tree can be created with the hidden "_symlinks" command of
+// the root command in github.com/mesosphere/dkp-cli-runtime/core/cmd/root.
+func HandleExec(prefixToStrip string) bool {
+	reexeced, err := HandleExecWithOptions(Options{Prefix: prefixToStrip})
+	if err != nil {
+		panic(err)
+	}
+	return reexeced
+}
@@ -10,7 +10,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+
+	symlinkexechandler "github.com/mesosphere/dkp-cli-runtime/symlink-reexec"
 )
 
 type HandlerTestSuite struct {
@@ -30,6 +33,13 @@ func (suite *HandlerTestSuite) SetupSuite() {
 	output, err := cmd.CombinedOutput()
 	suite.Require().NoError(err)
 	suite.T().Log(string(output))
+	cmd = exec.Command( //nolint:gosec // Building test binary into temporary directory.
+		"go", "build",
+		"-o", filepath.Join(suite.tempDir, "options"),
+		"testdata/options_main.go")
+	output, err = cmd.CombinedOutput()
+	suite.Require().NoError(err)
+	suite.T().Log(string(output))
 	suite.beforePATH = os.Getenv("PATH")
 	os.Setenv("PATH", suite.tempDir)
 }
@@ -95,6 +105,90 @@ func (suite *HandlerTestSuite) TestSymlinkInvocationWithSubcommands() {
 	)
 }
 
+func (suite *HandlerTestSuite) TestSymlinkInvocationWithAlias() {
+	symlinkTempDir := suite.T().TempDir()
+	symlinkPath := filepath.Join(symlinkTempDir, "kubectl-options")
+	os.Symlink(filepath.Join(suite.tempDir, "options"), symlinkPath)
+	cmd := exec.Command(
+		symlinkPath,
+		"--flag1",
+	)
+	output, err := cmd.CombinedOutput()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`"options" "kubectl" "get-all" "--flag1"`, string(output))
+}
+
+func (suite *HandlerTestSuite) TestNoReexecLoop() {
+	cmd := exec.Command(
+		"options",
+		"--flag1",
+	)
+	output, err := cmd.CombinedOutput()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(`"options" "loop" "--flag1"`, string(output))
+}
+
 func TestExampleTestSuite(t *testing.T) {
 	suite.Run(t, new(HandlerTestSuite))
 }
+
+func TestResolve(t *testing.T) {
+	opts := symlinkexechandler.Options{
+		Prefix: "dkp",
+		Aliases: map[string][]string{
+			"dkp-create-nodepool": {"create", "node-pool", "aws"},
+			"kubectl-dkp":         {"kubectl"},
+		},
+	}
+	tests := []struct {
+		argv0    string
+		args     []string
+		expected []string
+	}{
+		{argv0: "/usr/bin/dkp", args: []string{"--help"}},
+		{argv0: "/usr/bin/other", args: []string{"--help"}},
+		{
+			argv0:    "/usr/bin/dkp-create-cluster",
+			args:     []string{"--flag", "with spaces"},
+			expected: []string{"dkp", "create", "cluster", "--flag", "with spaces"},
+		},
+		{
+			argv0:    "dkp-create-node_pool",
+			expected: []string{"dkp", "create", "node-pool"},
+		},
+		{
+			argv0:    "dkp-create-nodepool",
+			args:     []string{"--flag"},
+			expected: []string{"dkp", "create", "node-pool", "aws", "--flag"},
+		},
+		{
+			argv0:    "/usr/local/bin/kubectl-dkp",
+			expected: []string{"dkp", "kubectl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.argv0, func(t *testing.T) {
+			resolved, ok := opts.Resolve(tt.argv0, tt.args)
+			assert.Equal(t, tt.expected != nil, ok)
+			assert.Equal(t, tt.expected, resolved)
+		})
+	}
+
+	resolved, ok := symlinkexechandler.Options{Prefix: "dkp-", Name: "/opt/dkp"}.Resolve("dkp-version", nil)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/opt/dkp", "version"}, resolved)
+}
+
+func TestHandleInProcess(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	opts := symlinkexechandler.Options{Prefix: "dkp"}
+
+	os.Args = []string{"/usr/bin/dkp-create-cluster", "--flag"}
+	assert.True(t, symlinkexechandler.HandleInProcess(opts))
+	assert.Equal(t, []string{"dkp", "create", "cluster", "--flag"}, os.Args)
+
+	// the rewritten arguments are not translated again
+	assert.False(t, symlinkexechandler.HandleInProcess(opts))
+	assert.Equal(t, []string{"dkp", "create", "cluster", "--flag"}, os.Args)
+}
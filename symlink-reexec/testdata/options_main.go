@@ -0,0 +1,32 @@
+// Copyright 2022 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	symlinkexechandler "github.com/mesosphere/dkp-cli-runtime/symlink-reexec"
+)
+
+func main() {
+	reexeced, err := symlinkexechandler.HandleExecWithOptions(symlinkexechandler.Options{
+		Prefix: "options",
+		Aliases: map[string][]string{
+			"kubectl-options": {"kubectl", "get-all"},
+			// re-execed with the same name, which is handled only once
+			"options": {"loop"},
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if reexeced {
+		return
+	}
+
+	fmt.Print(strings.Trim(fmt.Sprintf("%q", os.Args), "[]"))
+}